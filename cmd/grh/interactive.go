@@ -0,0 +1,201 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ymotongpoo/grh"
+)
+
+// reviewHelp は -i で置換箇所ごとに受け付ける操作の説明
+const reviewHelp = `y - この置換を適用する
+n - この置換を適用しない
+a - この置換と、以降の同じルールによる置換をすべて適用する
+e - ルールがマッチしたテキスト全体を置き換えるテキストを入力して適用する（空のまま Enter を押すとマッチしたテキストを削除する）
+q - この置換と、以降のすべての置換を適用せずに終了する
+? - このヘルプを表示する
+`
+
+// reviewSession は -i で置換箇所を1つずつ確認する際の状態を表す
+// 「同じルールをすべて適用」と「終了」の指定は、以降のファイルにも引き継ぐ
+type reviewSession struct {
+	in        *bufio.Reader
+	out       io.Writer
+	context   int              // 置換箇所の前後に表示する行数
+	acceptAll map[ruleKey]bool // すべて適用することにしたルール
+	quit      bool
+}
+
+// ruleKey はファイルをまたいで同じルールを識別するためのキー
+// フロントマターの grh の設定で文書ごとにルールの一覧が変わるため、ルールの番号（RuleIndex）は使えない。
+// インポートしたルールを同じIDで置き換えた場合にも区別できるよう、パターンと expected も含める
+type ruleKey struct {
+	id       string
+	pattern  string
+	expected string
+}
+
+// keyOf はルールの ruleKey を返す
+func keyOf(rule grh.Rule) ruleKey {
+	id := rule.ID
+	if id == "" {
+		id = rule.DerivedID()
+	}
+	return ruleKey{id: id, pattern: rule.CompiledPattern(), expected: rule.Expected}
+}
+
+// newReviewSession は新しいreviewSessionを作成する
+func newReviewSession(in io.Reader, out io.Writer, context int) *reviewSession {
+	return &reviewSession{
+		in:        bufio.NewReader(in),
+		out:       out,
+		context:   context,
+		acceptAll: make(map[ruleKey]bool),
+	}
+}
+
+// review は置換箇所を文書の先頭から順に確認し、適用することにした変更だけを反映した結果を返す
+func (s *reviewSession) review(filePath string, result *grh.ReplaceResult) (*grh.ReplaceResult, error) {
+	// 確認は文書中の位置の順に行う
+	order := make([]int, len(result.Changes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return result.Changes[order[i]].Position < result.Changes[order[j]].Position
+	})
+
+	accepted := make([]*grh.Change, len(result.Changes))
+	for n, i := range order {
+		if s.quit {
+			break
+		}
+		change := result.Changes[i]
+		if s.acceptAll[keyOf(change.Rule)] {
+			accepted[i] = &change
+			continue
+		}
+
+		s.show(filePath, result.Original, change, n+1, len(order))
+		edited, ok, err := s.ask(result, change)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			accepted[i] = &edited
+		}
+	}
+
+	var changes []grh.Change
+	for _, change := range accepted {
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return result.ApplyChanges(changes)
+}
+
+// show は置換箇所とその前後の行を表示する
+func (s *reviewSession) show(filePath, original string, change grh.Change, n, total int) {
+	lines := strings.SplitAfter(original, "\n")
+	target := change.Line - 1
+
+	fmt.Fprintf(s.out, "\n%s:%d:%d (%d/%d) %s: %s\n", filePath, change.Line, change.Column, n, total, change.Rule.ID, change.Rule.Expected)
+
+	lineStart := strings.LastIndex(original[:change.Position], "\n") + 1
+	end := change.Position + change.Length
+	for i := max(0, target-s.context); i <= min(len(lines)-1, target+s.context); i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		marker := " "
+		if i == target {
+			marker = ">"
+			// 置換箇所が複数行にわたる場合は、その範囲をすべて1行として示す
+			line = strings.TrimRight(original[lineStart:change.Position]+"[-"+original[change.Position:end]+"-]{+"+change.To+"+}"+original[end:lineEndOf(original, end)], "\r\n")
+		}
+		fmt.Fprintf(s.out, "%s %4d | %s\n", marker, i+1, line)
+	}
+}
+
+// lineEndOf は offset を含む行の末尾（改行の直前）の位置を返す
+func lineEndOf(text string, offset int) int {
+	if i := strings.IndexByte(text[offset:], '\n'); i >= 0 {
+		return offset + i
+	}
+	return len(text)
+}
+
+// ask は置換箇所をどう扱うかを尋ね、適用する場合は適用する変更（編集した場合は編集後の変更）を返す
+func (s *reviewSession) ask(result *grh.ReplaceResult, change grh.Change) (grh.Change, bool, error) {
+	for {
+		fmt.Fprint(s.out, "この置換を適用しますか [y,n,a,e,q,?]? ")
+		answer, err := s.readLine()
+		if errors.Is(err, io.EOF) {
+			s.quit = true
+			return change, false, nil
+		}
+		if err != nil {
+			return change, false, err
+		}
+
+		switch answer {
+		case "y":
+			return change, true, nil
+		case "n":
+			return change, false, nil
+		case "a":
+			s.acceptAll[keyOf(change.Rule)] = true
+			return change, true, nil
+		case "e":
+			// 入力は1行のため、マッチしたテキストが改行で終わる場合は末尾の改行を除いたテキストを置き換える
+			matched := strings.TrimRight(change.Matched, "\r\n")
+			fmt.Fprintf(s.out, "%q を置き換えるテキスト（空の場合は削除）: ", matched)
+			text, err := s.readLine()
+			if errors.Is(err, io.EOF) {
+				s.quit = true
+				return change, false, nil
+			}
+			if err != nil {
+				return change, false, err
+			}
+			return result.EditChange(change, text+change.Matched[len(matched):]), true, nil
+		case "q":
+			s.quit = true
+			return change, false, nil
+		default:
+			fmt.Fprint(s.out, reviewHelp)
+		}
+	}
+}
+
+// readLine は1行読み込む。入力が終わった場合は io.EOF を返す
+func (s *reviewSession) readLine() (string, error) {
+	line, err := s.in.ReadString('\n')
+	if errors.Is(err, io.EOF) && line != "" {
+		err = nil
+	}
+	if errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -15,13 +15,19 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/ymotongpoo/grh"
@@ -30,16 +36,49 @@ import (
 
 // CLIOptions はコマンドラインオプションを表す構造体
 type CLIOptions struct {
-	RulesYAML bool
-	RulesJSON bool
-	Rules     string
-	Verify    bool
-	Stdout    bool
-	Diff      bool
-	Replace   bool
-	Files     []string
+	RulesYAML     bool
+	RulesJSON     bool
+	Rules         string
+	Verify        bool
+	Stdout        bool
+	Diff          string
+	DiffContext   int
+	Color         bool
+	Replace       bool
+	Interactive   bool
+	Check         bool
+	Format        string
+	Include       stringListFlag
+	Exclude       stringListFlag
+	Ext           string
+	GitIgnore     bool
+	Jobs          int
+	StdinFilename string
+	Markdown      string
+	Verbose       bool
+	Files         []string
 }
 
+// stdinPath は標準入力から読み込むことを表すファイル名
+const stdinPath = "-"
+
+// defaultStdinFilename は --stdin-filename を指定しなかった場合に標準入力の内容を表示する際の名前
+const defaultStdinFilename = "<stdin>"
+
+// reviewContextLines は -i で置換箇所の前後に表示する行数
+const reviewContextLines = 2
+
+// 終了ステータス
+// flagパッケージが引数の解析エラーで2を使うため、検出時のステータスはそれと区別する
+const (
+	exitError    = 1 // 処理中にエラーが発生した
+	exitUsage    = 2 // オプションの組み合わせが正しくない（flagパッケージの引数の解析エラーと同じ）
+	exitFindings = 3 // --check でルールに該当する箇所が見つかった
+)
+
+// errFindingsDetected は --check でルールに該当する箇所が見つかったことを表す
+var errFindingsDetected = errors.New("rule violations found")
+
 // Statistics は処理統計を表す構造体
 type Statistics struct {
 	FilesProcessed   int
@@ -53,9 +92,16 @@ type FileStatistics struct {
 	FilePath     string
 	Replacements int
 	Modified     bool
+
+	findings []grh.Finding // --check で出力する該当箇所
 }
 
 func main() {
+	// grh test はルールのテストケースを実行するサブコマンド
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		os.Exit(runTestCommand(os.Args[2:]))
+	}
+
 	var opts CLIOptions
 
 	// コマンドラインフラグの定義
@@ -64,12 +110,29 @@ func main() {
 	flag.StringVar(&opts.Rules, "rules", "", "grhコマンドを実行する際のルールファイルを指定する")
 	flag.BoolVar(&opts.Verify, "verify", false, "指定したファイルがMarkdownとして正しいか確認する")
 	flag.BoolVar(&opts.Stdout, "stdout", false, "指定したファイルをルールファイルに基づいて置換した結果を標準出力に表示する")
-	flag.BoolVar(&opts.Diff, "diff", false, "指定したファイルとそれをルールファイルに基づいて置換した結果をUnified diff形式で出力する")
+	flag.Var(&diffModeFlag{mode: &opts.Diff}, "diff", "指定したファイルとそれをルールファイルに基づいて置換した結果をUnified diff形式で出力する（--diff=word, --diff=char で行内の変更箇所のみを表示）")
+	flag.IntVar(&opts.DiffContext, "diff-context", 3, "--diff で変更箇所の前後に表示するコンテキストの行数")
+	flag.BoolVar(&opts.Color, "color", false, "--diff=word, --diff=char の変更箇所を端末の色で表示する")
+	flag.BoolVar(&opts.Check, "check", false, "ルールに該当する箇所をファイル名:行:桁の形式で表示し、見つかった場合は終了ステータス3で終了する")
+	flag.StringVar(&opts.Format, "format", "", "--check の出力形式（"+strings.Join(grh.FindingsFormats(), ", ")+"）。指定した場合は --check と同様に動作する")
+	flag.Var(&opts.Include, "include", "ディレクトリ内のファイルのうち対象とするファイルのglobパターン（複数指定可）")
+	flag.Var(&opts.Exclude, "exclude", "対象から外すファイルやディレクトリのglobパターン（複数指定可）")
+	flag.StringVar(&opts.Ext, "ext", ".md,.markdown", "ディレクトリを指定した場合に対象とするファイルの拡張子（カンマ区切り）")
+	flag.BoolVar(&opts.GitIgnore, "gitignore", false, "ディレクトリを辿る際に .gitignore で除外されているファイルを対象から外す")
+	flag.IntVar(&opts.Jobs, "jobs", 1, "並列に処理するファイルの数（0の場合はCPU数）")
+	flag.StringVar(&opts.StdinFilename, "stdin-filename", "", "対象ファイルに - を指定して標準入力から読み込む際に、出力や該当箇所の表示に使うファイル名")
+	flag.StringVar(&opts.Markdown, "markdown", "", "置換の対象から外す範囲を求めるMarkdownの解析方法（regexp, goldmark）。ルールファイルの markdown より優先する")
+	flag.BoolVar(&opts.Verbose, "v", false, "文書ごとに適用するルールなど、処理の詳細をログに出力する")
+	flag.BoolVar(&opts.Verbose, "verbose", false, "文書ごとに適用するルールなど、処理の詳細をログに出力する")
 	flag.BoolVar(&opts.Replace, "r", false, "指定したファイルをルールファイルに基づいて置換し上書きする")
 	flag.BoolVar(&opts.Replace, "replace", false, "指定したファイルをルールファイルに基づいて置換し上書きする")
+	flag.BoolVar(&opts.Interactive, "i", false, "置換箇所を1つずつ確認し、適用することにした置換だけでファイルを上書きする")
+	flag.BoolVar(&opts.Interactive, "interactive", false, "置換箇所を1つずつ確認し、適用することにした置換だけでファイルを上書きする")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] 対象ファイル [対象ファイル...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] 対象ファイルまたはディレクトリ [対象ファイルまたはディレクトリ...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "対象ファイルに - を指定すると標準入力から読み込む\n")
+		fmt.Fprintf(os.Stderr, "ルールのテストケースを実行する場合は %s test [--rules ルールファイル]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nOptions:\n")
 		flag.PrintDefaults()
 	}
@@ -79,11 +142,27 @@ func main() {
 	// 残りの引数をファイルリストとして取得
 	opts.Files = flag.Args()
 
-	// ロガーの設定（--verifyオプション使用時はInfoレベル、それ以外はWarnレベル）
+	// --format を指定した場合は --check として扱う
+	if opts.Format != "" {
+		opts.Check = true
+	} else {
+		opts.Format = "text"
+	}
+
+	if err := validateOptions(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	// ロガーの設定（--verboseオプション使用時はDebugレベル、--verifyオプション使用時はInfoレベル、それ以外はWarnレベル）
 	logLevel := slog.LevelWarn
 	if opts.Verify {
 		logLevel = slog.LevelInfo
 	}
+	if opts.Verbose {
+		logLevel = slog.LevelDebug
+	}
 	
 	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
 		Level: logLevel,
@@ -91,11 +170,84 @@ func main() {
 	slog.SetDefault(logger)
 
 	if err := run(opts, logger); err != nil {
+		if errors.Is(err, errFindingsDetected) {
+			os.Exit(exitFindings)
+		}
 		logger.Error("Command failed", "error", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
 
+// validateOptions は同時に指定できないオプションの組み合わせを検出する
+func validateOptions(opts CLIOptions) error {
+	// -i は確認した置換だけでファイルを上書きするため、ファイルを変更しない出力のオプションとは組み合わせられない
+	if opts.Interactive {
+		var conflicts []string
+		if opts.Check {
+			conflicts = append(conflicts, "--check/--format")
+		}
+		if opts.Stdout {
+			conflicts = append(conflicts, "--stdout")
+		}
+		if opts.Diff != "" {
+			conflicts = append(conflicts, "--diff")
+		}
+		if len(conflicts) > 0 {
+			return fmt.Errorf("-i cannot be combined with %s", strings.Join(conflicts, ", "))
+		}
+	}
+	// ルールを読み込む前に --format の値を確認する
+	if opts.Check && !slices.Contains(grh.FindingsFormats(), opts.Format) {
+		return fmt.Errorf("unknown --format %q (supported: %s)", opts.Format, strings.Join(grh.FindingsFormats(), ", "))
+	}
+	return nil
+}
+
+// stringListFlag は複数回指定できる文字列のオプションを表す
+type stringListFlag []string
+
+func (f *stringListFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// diffModeFlag は --diff オプションの値を表す
+// 値を省略した場合（--diff）は行単位のUnified diffになる
+type diffModeFlag struct {
+	mode *string
+}
+
+func (f *diffModeFlag) String() string {
+	if f.mode == nil {
+		return ""
+	}
+	return *f.mode
+}
+
+func (f *diffModeFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*f.mode = "line"
+	case "false":
+		*f.mode = ""
+	default:
+		if _, err := grh.ParseDiffGranularity(value); err != nil {
+			return err
+		}
+		*f.mode = value
+	}
+	return nil
+}
+
+// IsBoolFlag は値なしの --diff を受け付けるために true を返す
+func (f *diffModeFlag) IsBoolFlag() bool {
+	return true
+}
+
 // 統計表示用のテンプレート
 const statsTemplate = `
 処理結果:
@@ -107,40 +259,65 @@ const statsTemplate = `
   {{.FilePath}}: {{.Replacements}}件の置換{{end}}{{end}}
 {{end}}`
 
-// printStatistics は統計情報を標準出力に表示する
-func printStatistics(stats Statistics) error {
+// loadConfig は rules で指定したルールファイル（空の場合は FindRuleFile で探したファイル）をインポートも含めて読み込む
+func loadConfig(rules string, loadOpts grh.LoadOptions) (*grh.Config, error) {
+	if rules != "" {
+		// 指定されたルールファイルを読み込み
+		config, err := grh.LoadConfigWithImportsOptions(rules, loadOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rules file %q: %w", rules, err)
+		}
+		return config, nil
+	}
+
+	// デフォルトのルールファイルを検索
+	ruleFile, err := grh.FindRuleFile("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rule file: %w", err)
+	}
+	config, err := grh.LoadConfigWithImportsOptions(ruleFile, loadOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rule file %q: %w", ruleFile, err)
+	}
+	return config, nil
+}
+
+// printStatistics は統計情報を w に表示する
+func printStatistics(w io.Writer, stats Statistics) error {
 	tmpl, err := template.New("stats").Parse(statsTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse template: %w", err)
 	}
 	
-	return tmpl.Execute(os.Stdout, stats)
+	return tmpl.Execute(w, stats)
 }
 
 func run(opts CLIOptions, logger *slog.Logger) error {
 	// ルールファイルの読み込み
-	var config *grh.Config
-	var err error
+	config, err := loadConfig(opts.Rules, grh.LoadOptions{})
+	if err != nil {
+		return err
+	}
 
-	if opts.Rules != "" {
-		// 指定されたルールファイルを読み込み
-		config, err = grh.LoadConfigWithImports(opts.Rules)
-		if err != nil {
-			return fmt.Errorf("failed to load rules file %q: %w", opts.Rules, err)
-		}
-	} else {
-		// デフォルトのルールファイルを検索
-		ruleFile, err := grh.FindRuleFile("")
-		if err != nil {
-			return fmt.Errorf("failed to find rule file: %w", err)
-		}
-		config, err = grh.LoadConfigWithImports(ruleFile)
-		if err != nil {
-			return fmt.Errorf("failed to load rule file %q: %w", ruleFile, err)
+	// --markdown はルールファイルの markdown より優先する
+	if opts.Markdown != "" {
+		if err := grh.ValidateMarkdownBackend(opts.Markdown); err != nil {
+			return fmt.Errorf("invalid --markdown: %w", err)
 		}
+		config.Markdown = opts.Markdown
+	}
+
+	// specFailures: warn のルールファイルで失敗したテストケースは警告として表示する
+	for _, warning := range config.SpecWarnings() {
+		logger.Warn("Spec failed", "error", warning)
+	}
+	// どのルールにも該当しなかった ignoreRules の条件は、IDの誤りなどの可能性があるため警告する
+	for _, warning := range config.IgnoreWarnings() {
+		logger.Warn("ignoreRules entry matched no rule", "error", warning)
 	}
 
 	logger.Info("Loaded configuration", "rules_count", len(config.Rules), "source_paths", config.SourcePaths)
+	logger.Debug("Effective rules", "rules", config.EnabledRules())
 
 	// --rules-yaml オプションの処理
 	if opts.RulesYAML {
@@ -157,6 +334,43 @@ func run(opts CLIOptions, logger *slog.Logger) error {
 		return fmt.Errorf("no files specified")
 	}
 
+	// 標準入力は1度しか読めないため、他のファイルとは組み合わせられない
+	if slices.Contains(opts.Files, stdinPath) {
+		if len(opts.Files) > 1 {
+			return fmt.Errorf("%q cannot be combined with other files", stdinPath)
+		}
+		if opts.Replace {
+			return fmt.Errorf("cannot use --replace with %q; use --stdout instead", stdinPath)
+		}
+		// 確認の応答を標準入力から読むため、標準入力の内容は処理できない
+		if opts.Interactive {
+			return fmt.Errorf("cannot use -i with %q", stdinPath)
+		}
+	}
+	if opts.StdinFilename == "" {
+		opts.StdinFilename = defaultStdinFilename
+	}
+
+	// ディレクトリを辿って処理対象のファイルを集める
+	collectOpts := grh.CollectOptions{
+		Include:   opts.Include,
+		Exclude:   opts.Exclude,
+		GitIgnore: opts.GitIgnore,
+	}
+	for _, ext := range strings.Split(opts.Ext, ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			collectOpts.Extensions = append(collectOpts.Extensions, ext)
+		}
+	}
+	files := opts.Files
+	if !slices.Contains(opts.Files, stdinPath) {
+		files, err = grh.CollectFiles(opts.Files, collectOpts)
+		if err != nil {
+			return fmt.Errorf("failed to collect files: %w", err)
+		}
+	}
+	logger.Info("Collected files", "files_count", len(files))
+
 	// Replacerを作成
 	replacer := grh.NewReplacerWithLogger(config, logger)
 
@@ -168,28 +382,57 @@ func run(opts CLIOptions, logger *slog.Logger) error {
 		FileStats: make([]FileStatistics, 0),
 	}
 
-	// 各ファイルを処理
-	for _, filePath := range opts.Files {
-		fileStat, err := processFile(filePath, opts, replacer, logger)
-		if err != nil {
-			return fmt.Errorf("failed to process file %q: %w", filePath, err)
-		}
-		
+	// -i では置換箇所を1つずつ確認するため、ファイルは1つずつ処理する
+	var session *reviewSession
+	if opts.Interactive {
+		session = newReviewSession(os.Stdin, os.Stdout, reviewContextLines)
+		opts.Jobs = 1
+	}
+
+	var findings []grh.Finding
+
+	// 各ファイルを処理（出力と統計は入力の順序どおりに扱う）
+	err = processFiles(files, opts, replacer, session, logger, func(fileStat FileStatistics, output []byte) {
+		os.Stdout.Write(output)
+
 		stats.FilesProcessed++
 		if fileStat.Modified {
 			stats.FilesModified++
 		}
 		stats.TotalReplacements += fileStat.Replacements
 		stats.FileStats = append(stats.FileStats, fileStat)
+		findings = append(findings, fileStat.findings...)
+	})
+	if err != nil {
+		return err
+	}
+
+	// --check の該当箇所をすべてのファイル分まとめて出力
+	if opts.Check {
+		if err := grh.WriteFindings(os.Stdout, opts.Format, findings); err != nil {
+			return fmt.Errorf("failed to write findings: %w", err)
+		}
 	}
 
 	// 統計情報を表示（--verify, --rules-yaml, --rules-json以外の場合）
-	if !opts.Verify && !opts.RulesYAML && !opts.RulesJSON {
-		if err := printStatistics(stats); err != nil {
+	// 機械可読な形式で該当箇所を出力する場合や、標準入力の置換結果をパイプで渡す場合は出力が壊れないよう表示しない
+	// 置換結果や差分、該当箇所を標準出力に出力する場合は、それに混ざらないよう標準エラー出力に表示する
+	pipe := slices.Contains(files, stdinPath) && (opts.Stdout || opts.Diff != "")
+	if !opts.Verify && !opts.RulesYAML && !opts.RulesJSON && !(opts.Check && opts.Format != "text") && !pipe {
+		var w io.Writer = os.Stdout
+		if opts.Stdout || opts.Diff != "" || opts.Check {
+			w = os.Stderr
+		}
+		if err := printStatistics(w, stats); err != nil {
 			logger.Warn("Failed to print statistics", "error", err)
 		}
 	}
 
+	// --check でルールに該当する箇所があった場合は専用の終了ステータスで知らせる
+	if opts.Check && stats.TotalReplacements > 0 {
+		return errFindingsDetected
+	}
+
 	return nil
 }
 
@@ -211,46 +454,166 @@ func outputRulesJSON(config *grh.Config) error {
 	return nil
 }
 
-func processFile(filePath string, opts CLIOptions, replacer *grh.Replacer, logger *slog.Logger) (FileStatistics, error) {
-	logger.Info("Processing file", "file_path", filePath)
+// fileResult は1ファイル分の処理結果を表す構造体
+type fileResult struct {
+	stat   FileStatistics
+	output bytes.Buffer
+	err    error
+	done   chan struct{}
+}
+
+// processFiles は opts.Jobs 個のワーカーでファイルを並列に処理する
+// 処理の完了順にかかわらず、handle は files の順序どおりに呼び出される
+// いずれかのファイルでエラーが発生した場合は、それ以降のファイルは処理せずにエラーを返す
+func processFiles(files []string, opts CLIOptions, replacer *grh.Replacer, session *reviewSession, logger *slog.Logger, handle func(FileStatistics, []byte)) error {
+	jobs := opts.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs > len(files) {
+		jobs = len(files)
+	}
+
+	results := make([]*fileResult, len(files))
+	for i := range results {
+		results[i] = &fileResult{done: make(chan struct{})}
+	}
+
+	indexes := make(chan int)
+	stop := make(chan struct{})
+
+	go func() {
+		defer close(indexes)
+		for i := range files {
+			select {
+			case indexes <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result := results[i]
+				result.stat, result.err = processFile(files[i], opts, replacer, session, logger, &result.output)
+				close(result.done)
+			}
+		}()
+	}
+
+	for i, result := range results {
+		<-result.done
+		if result.err != nil {
+			// 新たなファイルの処理を止め、処理中のファイルが終わるのを待ってから返す
+			close(stop)
+			wg.Wait()
+			return fmt.Errorf("failed to process file %q: %w", files[i], result.err)
+		}
+		handle(result.stat, result.output.Bytes())
+	}
+
+	wg.Wait()
+	return nil
+}
+
+func processFile(filePath string, opts CLIOptions, replacer *grh.Replacer, session *reviewSession, logger *slog.Logger, w io.Writer) (FileStatistics, error) {
+	// 標準入力の場合は --stdin-filename の名前で表示する
+	displayPath := filePath
+	if filePath == stdinPath {
+		displayPath = opts.StdinFilename
+	}
+
+	logger.Info("Processing file", "file_path", displayPath)
 
 	fileStat := FileStatistics{
-		FilePath:     filePath,
+		FilePath:     displayPath,
 		Replacements: 0,
 		Modified:     false,
 	}
 
 	// --verify オプションの処理
 	if opts.Verify {
-		err := verifyMarkdown(filePath, replacer, logger)
+		err := verifyMarkdown(filePath, displayPath, replacer, logger)
 		return fileStat, err
 	}
 
-	// ファイルを処理
-	result, err := replacer.ReplaceFile(filePath)
+	// ファイルを読み込む
+	var content []byte
+	var err error
+	if filePath == stdinPath {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(filePath)
+	}
 	if err != nil {
+		return fileStat, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// フロントマターの grh の設定がある場合は、それを適用したルールで置換する
+	// 設定の rules に書いたルールファイルは文書と同じディレクトリから探す
+	// フロントマターを解析できない場合（--- で始まる区切り線など）は、警告して通常のルールで置換する
+	doc, err := replacer.ForDocument(string(content), filepath.Dir(displayPath))
+	switch {
+	case errors.Is(err, grh.ErrInvalidFrontMatter):
+		logger.Warn("Ignoring front matter settings", "file_path", displayPath, "error", err)
+	case err != nil:
 		return fileStat, err
+	default:
+		replacer = doc
 	}
+	result := replacer.ReplaceString(string(content))
 
 	// 統計情報を更新
 	fileStat.Replacements = len(result.Changes)
 	fileStat.Modified = result.Changed
 
+	// --check オプションの処理
+	if opts.Check {
+		fileStat.findings = grh.NewFindings(displayPath, result)
+		fileStat.Replacements = len(fileStat.findings)
+		return fileStat, nil
+	}
+
 	// --stdout オプションの処理
 	if opts.Stdout {
-		fmt.Print(result.Result)
+		fmt.Fprint(w, result.Result)
 		return fileStat, nil
 	}
 
 	// --diff オプションの処理
-	if opts.Diff {
-		diff := replacer.GenerateDiff(result, filePath)
+	if opts.Diff != "" {
+		granularity, err := grh.ParseDiffGranularity(opts.Diff)
+		if err != nil {
+			return fileStat, err
+		}
+		diffOpts := grh.DefaultDiffOptions()
+		diffOpts.Context = opts.DiffContext
+		diffOpts.Granularity = granularity
+		diffOpts.Color = opts.Color
+		diff := replacer.GenerateDiffWithOptions(result, displayPath, diffOpts)
 		if diff != "" {
-			fmt.Print(diff)
+			fmt.Fprint(w, diff)
 		}
 		return fileStat, nil
 	}
 
+	// -i オプションの処理
+	if opts.Interactive {
+		reviewed, err := session.review(displayPath, result)
+		if err != nil {
+			return fileStat, err
+		}
+		fileStat.Replacements = len(reviewed.Changes)
+		fileStat.Modified = reviewed.Changed
+		err = replacer.WriteResult(reviewed, filePath)
+		return fileStat, err
+	}
+
 	// --replace オプションの処理
 	if opts.Replace {
 		err := replacer.WriteResult(result, filePath)
@@ -259,41 +622,49 @@ func processFile(filePath string, opts CLIOptions, replacer *grh.Replacer, logge
 
 	// デフォルト動作：変更があった場合のみ通知
 	if result.Changed {
-		logger.Info("File would be changed", 
-			"file_path", filePath, 
+		logger.Info("File would be changed",
+			"file_path", displayPath,
 			"changes_count", len(result.Changes))
 		
 		// 変更内容の概要を表示
 		for _, change := range result.Changes {
 			logger.Info("Rule would apply", 
 				"rule_index", change.RuleIndex,
-				"expected", change.Rule.Expected)
+				"expected", change.Rule.Expected,
+				"line", change.Line,
+				"column", change.Column,
+				"from", change.From,
+				"to", change.To)
 		}
 	} else {
-		logger.Info("No changes needed", "file_path", filePath)
+		logger.Info("No changes needed", "file_path", displayPath)
 	}
 
 	return fileStat, nil
 }
 
-func verifyMarkdown(filePath string, replacer *grh.Replacer, logger *slog.Logger) error {
+func verifyMarkdown(filePath, displayPath string, replacer *grh.Replacer, logger *slog.Logger) error {
 	// ファイルの拡張子をチェック
-	ext := strings.ToLower(filepath.Ext(filePath))
+	ext := strings.ToLower(filepath.Ext(displayPath))
 	if ext != ".md" && ext != ".markdown" {
-		logger.Warn("File is not a Markdown file", "file_path", filePath, "extension", ext)
+		logger.Warn("File is not a Markdown file", "file_path", displayPath, "extension", ext)
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+	var reader io.Reader = os.Stdin
+	if filePath != stdinPath {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+		reader = file
 	}
-	defer file.Close()
 
-	err = replacer.ValidateMarkdown(file)
+	err := replacer.ValidateMarkdown(reader)
 	if err != nil {
 		return fmt.Errorf("markdown validation failed: %w", err)
 	}
 
-	logger.Info("Markdown validation passed", "file_path", filePath)
+	logger.Info("Markdown validation passed", "file_path", displayPath)
 	return nil
 }
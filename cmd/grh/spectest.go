@@ -0,0 +1,145 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ymotongpoo/grh"
+)
+
+// specTestSummary は grh test の集計結果を表す構造体
+type specTestSummary struct {
+	RulesPassed  int
+	RulesFailed  int
+	RulesNoSpecs int
+	SpecsPassed  int
+	SpecsFailed  int
+
+	DocumentsPassed int // ルールファイルの specs に書いた文書全体のテストケース
+	DocumentsFailed int
+}
+
+// failed は失敗したテストケースがあったかを返す
+func (s specTestSummary) failed() bool {
+	return s.RulesFailed > 0 || s.DocumentsFailed > 0
+}
+
+// runTestCommand は grh test サブコマンドを実行し、終了ステータスを返す
+// インポートしたファイルも含めたすべてのルールのテストケースと文書全体のテストケースを実行し、結果を表示する
+func runTestCommand(args []string) int {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	rules := fs.String("rules", "", "テストケースを実行するルールファイルを指定する")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s test [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "ルールファイルのすべてのテストケースを実行し、失敗したものがあれば終了ステータス1で終了する\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	}))
+
+	// テストケースの失敗で読み込みを止めないよう、検証せずに読み込む
+	config, err := loadConfig(*rules, grh.LoadOptions{SkipSpecs: true})
+	if err != nil {
+		logger.Error("Command failed", "error", err)
+		return exitError
+	}
+
+	for _, warning := range config.IgnoreWarnings() {
+		logger.Warn("ignoreRules entry matched no rule", "error", warning)
+	}
+
+	summary, err := runSpecs(config, os.Stdout)
+	if err != nil {
+		logger.Error("Command failed", "error", err)
+		return exitError
+	}
+	if summary.failed() {
+		return exitError
+	}
+	return 0
+}
+
+// runSpecs は config のすべてのルールのテストケースを実行し、結果を w に書き出す
+func runSpecs(config *grh.Config, w io.Writer) (specTestSummary, error) {
+	var summary specTestSummary
+
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if len(rule.Specs) == 0 {
+			summary.RulesNoSpecs++
+			continue
+		}
+
+		results, err := rule.RunSpecs()
+		if err != nil {
+			return summary, fmt.Errorf("%s: failed to run specs for rule %s: %w", rule.Source(), rule.ID, err)
+		}
+
+		var failed []grh.SpecResult
+		for _, result := range results {
+			if !result.Passed {
+				failed = append(failed, result)
+			}
+		}
+		summary.SpecsPassed += len(results) - len(failed)
+		summary.SpecsFailed += len(failed)
+
+		if len(failed) == 0 {
+			summary.RulesPassed++
+			fmt.Fprintf(w, "ok   %s %s (%s): %d specs\n", rule.Source(), rule.ID, rule.Expected, len(results))
+			continue
+		}
+
+		summary.RulesFailed++
+		fmt.Fprintf(w, "FAIL %s %s (%s): %d of %d specs failed\n", rule.Source(), rule.ID, rule.Expected, len(failed), len(results))
+		for _, result := range failed {
+			fmt.Fprintf(w, "    %s: %s\n", result.Spec.Source(), indent(result.Description()))
+		}
+	}
+
+	// 文書全体のテストケースはすべてのルールを適用した結果を検証する
+	for _, result := range config.RunDocumentSpecs() {
+		if result.Passed {
+			summary.DocumentsPassed++
+			fmt.Fprintf(w, "ok   %s document\n", result.Spec.Source())
+			continue
+		}
+		summary.DocumentsFailed++
+		fmt.Fprintf(w, "FAIL %s document\n", result.Spec.Source())
+		fmt.Fprintf(w, "    %s\n", indent(result.Description()))
+	}
+
+	fmt.Fprintf(w, "\nrules: %d passed, %d failed, %d without specs\n", summary.RulesPassed, summary.RulesFailed, summary.RulesNoSpecs)
+	fmt.Fprintf(w, "specs: %d passed, %d failed\n", summary.SpecsPassed, summary.SpecsFailed)
+	if summary.DocumentsPassed+summary.DocumentsFailed > 0 {
+		fmt.Fprintf(w, "documents: %d passed, %d failed\n", summary.DocumentsPassed, summary.DocumentsFailed)
+	}
+	return summary, nil
+}
+
+// indent は複数行のテキストの2行目以降を結果の詳細と同じ位置まで字下げする
+func indent(text string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(text, "\n"), "\n", "\n    ")
+}
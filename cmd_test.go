@@ -15,6 +15,8 @@
 package grh
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -74,6 +76,11 @@ func TestCLI_RulesYAML(t *testing.T) {
 	if !strings.Contains(yamlOutput, "rules:") {
 		t.Error("YAML output should contain 'rules:'")
 	}
+
+	// ignoreRules の pattern と比較するパターンは、expected だけのルールでも表示する
+	if !strings.Contains(yamlOutput, "compiledPattern: '[CcＣｃ][OoＯｏ][OoＯｏ][KkＫｋ][IiＩｉ][EeＥｅ]'") {
+		t.Errorf("YAML output should contain the compiled pattern, got:\n%s", yamlOutput)
+	}
 }
 
 func TestCLI_RulesJSON(t *testing.T) {
@@ -99,6 +106,10 @@ func TestCLI_RulesJSON(t *testing.T) {
 	if !strings.Contains(jsonOutput, `"rules":`) {
 		t.Error("JSON output should contain '\"rules\":'")
 	}
+
+	if !strings.Contains(jsonOutput, `"compiledPattern": "[CcＣｃ][OoＯｏ][OoＯｏ][KkＫｋ][IiＩｉ][EeＥｅ]"`) {
+		t.Errorf("JSON output should contain the compiled pattern, got:\n%s", jsonOutput)
+	}
 }
 
 func TestCLI_Stdout(t *testing.T) {
@@ -156,6 +167,51 @@ func TestCLI_Diff(t *testing.T) {
 	if !strings.Contains(diffOutput, "+++") {
 		t.Error("Diff output should contain '+++'")
 	}
+
+	// 差分を git apply などに渡せるよう、統計情報は標準エラー出力に表示する
+	for _, args := range [][]string{
+		{"--diff", "testdata/doc/sample.md"},
+		{"--stdout", "testdata/doc/sample.md"},
+		{"--check", "testdata/doc/sample.md"},
+	} {
+		var stdout, stderr strings.Builder
+		cmd = exec.Command("./grh_test", append([]string{"--rules", "testdata/yaml/simple.yml"}, args...)...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		cmd.Run()
+		if strings.Contains(stdout.String(), "処理結果") {
+			t.Errorf("%v: stdout should not contain statistics, got:\n%s", args, stdout.String())
+		}
+		if !strings.Contains(stderr.String(), "処理結果") {
+			t.Errorf("%v: stderr should contain statistics, got:\n%s", args, stderr.String())
+		}
+	}
+}
+
+func TestCLI_DiffChar(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	// --diff=charオプションをテスト
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--diff=char", "testdata/doc/sample.md")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+
+	// 行全体ではなく変更された文字だけが強調されているかチェック
+	diffOutput := string(output)
+	if !strings.Contains(diffOutput, "これは[-c-]{+C+}ookie") {
+		t.Errorf("Diff output should highlight only the changed characters, got:\n%s", diffOutput)
+	}
+
+	if strings.Contains(diffOutput, "\n-これは") {
+		t.Error("Diff output should not contain whole removed lines")
+	}
 }
 
 func TestCLI_Replace(t *testing.T) {
@@ -203,6 +259,563 @@ func TestCLI_Replace(t *testing.T) {
 	}
 }
 
+func TestCLI_Interactive(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.md")
+	original := "cookieとjqueryとcookie\njqueryとcookie\n"
+	if err := os.WriteFile(testFile, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// 1つ目のcookieは適用しない、jqueryは編集して適用、2つ目以降のcookieはすべて適用、2つ目のjqueryは適用しない
+	// 編集ではルールがマッチしたテキスト全体（jquery）を置き換えるテキストを入力する
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "-i", testFile)
+	cmd.Stdin = strings.NewReader("n\ne\njQuery!\na\nn\n")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+	if !strings.Contains(string(output), "[-c-]{+C+}ookie") {
+		t.Errorf("Output should show the change in context, got:\n%s", output)
+	}
+	if !strings.Contains(string(output), `"jquery" を置き換えるテキスト`) {
+		t.Errorf("Output should ask for the text replacing the whole match, got:\n%s", output)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if want := "cookieとjQuery!とCookie\njqueryとCookie\n"; string(content) != want {
+		t.Errorf("File content = %q, want %q", content, want)
+	}
+
+	// 前のルールの置換結果をさらに置換した変更は、前の変更とまとめて1つの置換として確認する
+	rulesFile := filepath.Join(tempDir, "chained.yml")
+	rules := "version: 1\nrules:\n  - expected: サーバー$1\n    pattern: サーバ([^ー]|$)\n  - expected: Webサーバー\n    pattern: WEBサーバー\n"
+	if err := os.WriteFile(rulesFile, []byte(rules), 0644); err != nil {
+		t.Fatalf("Failed to create rules: %v", err)
+	}
+	chainedFile := filepath.Join(tempDir, "chained.md")
+	if err := os.WriteFile(chainedFile, []byte("WEBサーバ\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	cmd = exec.Command("./grh_test", "--rules", rulesFile, "-i", chainedFile)
+	cmd.Stdin = strings.NewReader("n\n")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+	if !strings.Contains(string(output), "(1/1)") || !strings.Contains(string(output), "W[-EBサーバ-]{+ebサーバー+}") {
+		t.Errorf("Output should show the chained replacements as one change, got:\n%s", output)
+	}
+	content, err = os.ReadFile(chainedFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if want := "WEBサーバ\n"; string(content) != want {
+		t.Errorf("File content = %q, want %q", content, want)
+	}
+
+	// 後のルールが前の置換結果の中に複数回マッチしても、まとめた変更は重ならずに適用できる
+	repeatedRules := filepath.Join(tempDir, "repeated.yml")
+	if err := os.WriteFile(repeatedRules, []byte("version: 1\nrules:\n  - expected: ｂｂ\n    pattern: B\n  - expected: c\n    pattern: a?ｂ\n"), 0644); err != nil {
+		t.Fatalf("Failed to create rules: %v", err)
+	}
+	if err := os.WriteFile(chainedFile, []byte("aB\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	cmd = exec.Command("./grh_test", "--rules", repeatedRules, "-i", chainedFile)
+	cmd.Stdin = strings.NewReader("y\n")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+	if !strings.Contains(string(output), "総置換回数: 1") {
+		t.Errorf("Output should count the merged change once, got:\n%s", output)
+	}
+	content, err = os.ReadFile(chainedFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if want := "cc\n"; string(content) != want {
+		t.Errorf("File content = %q, want %q", content, want)
+	}
+
+	// 編集したテキストはマッチしたテキスト全体を置き換えるが、マッチしたテキストの末尾の改行（パターンの文脈）は残す
+	// 空のまま入力した場合はマッチしたテキストを削除する
+	contextFile := filepath.Join(tempDir, "context.md")
+	for _, tt := range []struct {
+		answer string
+		want   string
+	}{
+		{answer: "サーバーー", want: "サーバーー\nfoo\n"},
+		{answer: "", want: "\nfoo\n"},
+	} {
+		if err := os.WriteFile(contextFile, []byte("サーバ\nfoo\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		cmd = exec.Command("./grh_test", "--rules", rulesFile, "-i", contextFile)
+		cmd.Stdin = strings.NewReader("e\n" + tt.answer + "\n")
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Command failed: %v, output: %s", err, output)
+		}
+		if !strings.Contains(string(output), `"サーバ" を置き換えるテキスト（空の場合は削除）`) {
+			t.Errorf("Output should ask for the text replacing the match, got:\n%s", output)
+		}
+		content, err = os.ReadFile(contextFile)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != tt.want {
+			t.Errorf("answer %q: file content = %q, want %q", tt.answer, content, tt.want)
+		}
+	}
+
+	// ファイルを変更しない出力のオプションとは組み合わせられない
+	for _, option := range []string{"--check", "--format=json", "--stdout", "--diff"} {
+		cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "-i", option, testFile)
+		cmd.Stdin = strings.NewReader("a\na\n")
+		output, err := cmd.CombinedOutput()
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok || exitErr.ExitCode() != 2 {
+			t.Errorf("-i %s: expected exit status 2, got err = %v, output: %s", option, err, output)
+		}
+		if !strings.Contains(string(output), "-i cannot be combined with") {
+			t.Errorf("-i %s: output should explain the conflict, got:\n%s", option, output)
+		}
+	}
+}
+
+func TestCLI_InteractiveFrontMatter(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	// a.md ではフロントマターで Cookie のルールを無効にしているため、ルールの番号がファイルごとに異なる
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"a.md": "---\ngrh: {disable: [{expected: Cookie}]}\n---\njquery\n",
+		"b.md": "cookie と jquery\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	// a.md の jquery はすべて適用し、b.md の cookie は適用しない
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "-i",
+		filepath.Join(tempDir, "a.md"), filepath.Join(tempDir, "b.md"))
+	cmd.Stdin = strings.NewReader("a\nn\n")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+
+	wants := map[string]string{
+		"a.md": "---\ngrh: {disable: [{expected: Cookie}]}\n---\njQuery\n",
+		"b.md": "cookie と jQuery\n",
+	}
+	for name, want := range wants {
+		content, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != want {
+			t.Errorf("%s = %q, want %q", name, content, want)
+		}
+	}
+}
+
+func TestCLI_Check(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	// ルールに該当する箇所があるファイルをチェック
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--check", "testdata/doc/sample.md")
+	output, err := cmd.Output()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 3 {
+		t.Fatalf("Expected exit status 3, got err = %v, output: %s", err, output)
+	}
+
+	checkOutput := string(output)
+	if !strings.Contains(checkOutput, `testdata/doc/sample.md:3:4: "cookie" -> "Cookie"`) {
+		t.Errorf("Check output should contain file:line:col of the finding, got:\n%s", checkOutput)
+	}
+
+	// ファイルが変更されていないかチェック
+	content, err := os.ReadFile("testdata/doc/sample.md")
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !strings.Contains(string(content), "cookie") {
+		t.Error("File should not be modified in check mode")
+	}
+
+	// ルールに該当する箇所がないファイルは正常終了する
+	tempDir := t.TempDir()
+	cleanFile := filepath.Join(tempDir, "clean.md")
+	if err := os.WriteFile(cleanFile, []byte("This is a Cookie"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--check", cleanFile)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Command should succeed for a clean file: %v, output: %s", err, output)
+	}
+}
+
+func TestCLI_CheckFormat(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	// --format sarif は --check と同様に動作し、標準出力にはSARIFのみを出力する
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--format", "sarif", "testdata/doc/sample.md")
+	output, err := cmd.Output()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 3 {
+		t.Fatalf("Expected exit status 3, got err = %v, output: %s", err, output)
+	}
+
+	var sarif map[string]any
+	if err := json.Unmarshal(output, &sarif); err != nil {
+		t.Fatalf("Output should be valid SARIF JSON: %v, output: %s", err, output)
+	}
+	if sarif["version"] != "2.1.0" {
+		t.Errorf("SARIF version = %v, want 2.1.0", sarif["version"])
+	}
+
+	// 対応していない形式は、ルールを読み込む前に使い方の誤りとして終了する
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/notfound.yml", "--format", "json", "testdata/doc/sample.md")
+	output, err = cmd.CombinedOutput()
+	exitErr, ok = err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 2 {
+		t.Fatalf("Expected exit status 2, got err = %v, output: %s", err, output)
+	}
+	if !strings.Contains(string(output), `unknown --format "json"`) {
+		t.Errorf("Output should report the unknown format, got:\n%s", output)
+	}
+}
+
+func TestCLI_Directory(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	// テスト用のディレクトリ構成を作成
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"a.md":        "cookie",
+		"sub/b.md":    "jquery",
+		"sub/c.txt":   "cookie",
+		"drafts/d.md": "cookie",
+	}
+	for name, content := range files {
+		p := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	// ディレクトリを再帰的に処理し、統計情報は1つにまとめて表示する
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--exclude", "drafts", "--replace", tempDir)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+
+	if !strings.Contains(string(output), "処理ファイル数: 2") {
+		t.Errorf("Statistics should count 2 files, got:\n%s", output)
+	}
+
+	wants := map[string]string{
+		"a.md":        "Cookie",
+		"sub/b.md":    "jQuery",
+		"sub/c.txt":   "cookie", // 拡張子が対象外
+		"drafts/d.md": "cookie", // --exclude で除外
+	}
+	for name, want := range wants {
+		content, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != want {
+			t.Errorf("%s = %q, want %q", name, content, want)
+		}
+	}
+}
+
+func TestCLI_Jobs(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	// 処理時間がばらつくように内容の長さが異なるファイルを作成
+	tempDir := t.TempDir()
+	var files []string
+	for i := 0; i < 20; i++ {
+		p := filepath.Join(tempDir, fmt.Sprintf("doc%02d.md", i))
+		content := fmt.Sprintf("file %d: %s\n", i, strings.Repeat("cookie and jquery\n", (20-i)*50))
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		files = append(files, p)
+	}
+
+	run := func(jobs string) (string, string) {
+		args := append([]string{"--rules", "testdata/yaml/simple.yml", "--jobs", jobs, "--diff"}, files...)
+		var stderr strings.Builder
+		cmd := exec.Command("./grh_test", args...)
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Command failed: %v, output: %s", err, output)
+		}
+		return string(output), stderr.String()
+	}
+
+	// 並列に処理しても出力は入力の順序どおりで、逐次処理と同じになる
+	sequential, _ := run("1")
+	parallel, stats := run("8")
+	if parallel != sequential {
+		t.Error("Output with --jobs 8 should be identical to --jobs 1")
+	}
+	// --diff の場合、統計情報は標準エラー出力に表示する
+	if !strings.Contains(stats, "処理ファイル数: 20") {
+		t.Errorf("Statistics should count 20 files, got:\n%s", stats)
+	}
+}
+
+func TestCLI_Stdin(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	input := "これはcookieを使用したjqueryのサンプルです。\n"
+
+	// --stdout では置換結果だけを標準出力に出力する
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--stdout", "-")
+	cmd.Stdin = strings.NewReader(input)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+	if want := "これはCookieを使用したjQueryのサンプルです。\n"; string(output) != want {
+		t.Errorf("Stdout = %q, want %q", output, want)
+	}
+
+	// --diff では --stdin-filename の名前を使う
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--diff", "--stdin-filename", "content/post.md", "-")
+	cmd.Stdin = strings.NewReader(input)
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+	if !strings.HasPrefix(string(output), "--- a/content/post.md\n+++ b/content/post.md\n") {
+		t.Errorf("Diff should use --stdin-filename, got:\n%s", output)
+	}
+
+	// --check でも --stdin-filename の名前で該当箇所を表示する
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--check", "--stdin-filename", "content/post.md", "-")
+	cmd.Stdin = strings.NewReader(input)
+	output, err = cmd.Output()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 3 {
+		t.Fatalf("Expected exit status 3, got err = %v, output: %s", err, output)
+	}
+	if !strings.Contains(string(output), `content/post.md:1:4: "cookie" -> "Cookie"`) {
+		t.Errorf("Check output should use --stdin-filename, got:\n%s", output)
+	}
+
+	// 標準入力は上書きできない
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--replace", "-")
+	cmd.Stdin = strings.NewReader(input)
+	if output, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("--replace with stdin should fail, output: %s", output)
+	}
+}
+
+func TestCLI_Test(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	// すべてのテストケースが成功した場合は正常終了する
+	cmd = exec.Command("./grh_test", "test", "--rules", "grh.yaml")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+	if !strings.Contains(string(output), "specs: ") || strings.Contains(string(output), "FAIL") {
+		t.Errorf("Unexpected output:\n%s", output)
+	}
+
+	// インポートしたファイルも含め、失敗したテストケースをすべて表示する
+	tempDir := t.TempDir()
+	shared := `version: 1
+rules:
+  - id: javascript
+    expected: JavaScript
+    specs:
+      - from: javascript
+        to: JavaScprit
+      - from: JAVASCRIPT
+        to: JavaScript
+  - id: cookie
+    expected: Cookie
+    specs:
+      - from: cookie
+        to: Cookie
+`
+	mainRules := `version: 1
+imports:
+  - path: shared.yml
+rules:
+  - id: jquery
+    expected: jQuery
+    pattern: "[jJ][qQ][uU][eE][rR][yY]"
+    specs:
+      - from: jquery
+        to: JQuery
+specs:
+  - from: "jquery と cookie"
+    to: "jQuery と Cookie"
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "shared.yml"), []byte(shared), 0644); err != nil {
+		t.Fatalf("Failed to write rule file: %v", err)
+	}
+	rulesPath := filepath.Join(tempDir, "grh.yml")
+	if err := os.WriteFile(rulesPath, []byte(mainRules), 0644); err != nil {
+		t.Fatalf("Failed to write rule file: %v", err)
+	}
+
+	cmd = exec.Command("./grh_test", "test", "--rules", rulesPath)
+	output, err = cmd.Output()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("Expected exit status 1, got err = %v, output: %s", err, output)
+	}
+
+	sharedPath := filepath.Join(tempDir, "shared.yml")
+	for _, want := range []string{
+		"FAIL " + sharedPath + ":3:5 javascript (JavaScript): 1 of 2 specs failed",
+		"    " + sharedPath + ":6:9: \"javascript\" expected \"JavaScprit\", but got \"JavaScript\"",
+		"ok   " + sharedPath + ":10:5 cookie (Cookie): 1 specs",
+		"FAIL " + rulesPath + ":5:5 jquery (jQuery): 1 of 1 specs failed",
+		"rules: 1 passed, 2 failed, 0 without specs",
+		"specs: 2 passed, 2 failed",
+		"ok   " + rulesPath + ":12:5 document",
+		"documents: 1 passed, 0 failed",
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, output)
+		}
+	}
+
+	// specFailures: warn の場合は通常の実行は警告だけで続ける
+	warned := strings.Replace(shared, "version: 1\n", "version: 1\nspecFailures: warn\n", 1)
+	if err := os.WriteFile(sharedPath, []byte(warned), 0644); err != nil {
+		t.Fatalf("Failed to write rule file: %v", err)
+	}
+	if err := os.WriteFile(rulesPath, []byte(strings.Replace(mainRules, "JQuery", "jQuery", 1)), 0644); err != nil {
+		t.Fatalf("Failed to write rule file: %v", err)
+	}
+	cmd = exec.Command("./grh_test", "--rules", rulesPath, "--stdout", "-")
+	cmd.Stdin = strings.NewReader("javascript\n")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v, stderr: %s", err, stderr.String())
+	}
+	if string(output) != "JavaScript\n" {
+		t.Errorf("Stdout = %q, want %q", output, "JavaScript\n")
+	}
+	if !strings.Contains(stderr.String(), "Spec failed") {
+		t.Errorf("Stderr should contain spec warning, got: %s", stderr.String())
+	}
+
+	// grh test は specFailures: warn でも失敗として扱う
+	cmd = exec.Command("./grh_test", "test", "--rules", rulesPath)
+	if output, err := cmd.Output(); err == nil {
+		t.Errorf("grh test should fail with specFailures: warn, output: %s", output)
+	}
+}
+
+func TestCLI_TestFrontMatter(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	// テストケースは frontMatterFields の設定も含め、実際の置換と同じ設定で評価する
+	rules := `version: 1
+frontMatterFields: [title]
+rules:
+  - expected: サーバ
+    pattern: サーバー
+    specs:
+      - from: "---\ntitle: サーバー\n---\n"
+        to: "---\ntitle: サーバ\n---\n"
+        document: true
+specs:
+  - from: "---\ntitle: サーバーの設定\nslug: サーバー\n---\nサーバー\n"
+    to: "---\ntitle: サーバの設定\nslug: サーバー\n---\nサーバ\n"
+`
+	rulesPath := filepath.Join(t.TempDir(), "grh.yml")
+	if err := os.WriteFile(rulesPath, []byte(rules), 0644); err != nil {
+		t.Fatalf("Failed to write rule file: %v", err)
+	}
+
+	cmd = exec.Command("./grh_test", "test", "--rules", rulesPath)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+	for _, want := range []string{"specs: 1 passed, 0 failed", "documents: 1 passed, 0 failed"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
 func TestCLI_Verify(t *testing.T) {
 	// grhコマンドをビルド
 	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
@@ -224,3 +837,104 @@ func TestCLI_Verify(t *testing.T) {
 		t.Error("Should contain Markdown validation success message")
 	}
 }
+
+func TestCLI_FrontMatterSettings(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	// 文書のフロントマターでルールを無効にし、文書と同じディレクトリのルールファイルを追加する
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"extra.yml": "version: 1\nrules:\n  - id: server\n    expected: サーバ\n    pattern: サーバー\n",
+		"a.md":      "---\ntitle: 記事\ngrh:\n  disable: [{expected: jQuery}]\n  rules: extra.yml\n---\ncookie と jquery のサーバー\n",
+		"b.md":      "cookie と jquery のサーバー\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--verbose", "--replace",
+		filepath.Join(tempDir, "a.md"), filepath.Join(tempDir, "b.md"))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Command failed: %v, output: %s", err, output)
+	}
+
+	wants := map[string]string{
+		"a.md": "---\ntitle: 記事\ngrh:\n  disable: [{expected: jQuery}]\n  rules: extra.yml\n---\nCookie と jquery のサーバ\n",
+		"b.md": "Cookie と jQuery のサーバー\n",
+	}
+	for name, want := range wants {
+		content, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != want {
+			t.Errorf("%s = %q, want %q", name, content, want)
+		}
+	}
+
+	// --verbose では文書ごとに適用するルールをログに出力する
+	var effective [][]string
+	for _, line := range strings.Split(string(output), "\n") {
+		var entry struct {
+			Msg   string   `json:"msg"`
+			Rules []string `json:"rules"`
+		}
+		if json.Unmarshal([]byte(line), &entry) == nil && entry.Msg == "Effective rules" {
+			effective = append(effective, entry.Rules)
+		}
+	}
+	if len(effective) != 2 {
+		t.Fatalf("Effective rules should be logged for the configuration and a.md, got %d:\n%s", len(effective), output)
+	}
+	if got := strings.Join(effective[1], ","); strings.Contains(got, "jQuery") || !strings.Contains(got, "server (サーバ)") {
+		t.Errorf("Effective rules for a.md = %q", effective[1])
+	}
+}
+
+func TestCLI_InvalidFrontMatter(t *testing.T) {
+	// grhコマンドをビルド
+	cmd := exec.Command("go", "build", "-o", "grh_test", "./cmd/grh")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grh command: %v", err)
+	}
+	defer os.Remove("grh_test")
+
+	// 解析できないフロントマターのファイルがあっても、警告して残りのファイルも処理する
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"hr.md":     "---\nIntro cookie text\n---\n\ncookie body\n",
+		"broken.md": "---\ntitle: \"a\n---\ncookie body\n",
+		"doc.md":    "cookie\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	cmd = exec.Command("./grh_test", "--rules", "testdata/yaml/simple.yml", "--check",
+		filepath.Join(tempDir, "hr.md"), filepath.Join(tempDir, "broken.md"), filepath.Join(tempDir, "doc.md"))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 3 {
+		t.Fatalf("Expected exit status 3, got err = %v, output: %s%s", err, output, stderr.String())
+	}
+	for _, name := range []string{"hr.md:5:1", "broken.md:4:1", "doc.md:1:1"} {
+		if !strings.Contains(string(output), name) {
+			t.Errorf("Check output should contain %s, got:\n%s", name, output)
+		}
+	}
+	if got := strings.Count(stderr.String(), "Ignoring front matter settings"); got != 2 {
+		t.Errorf("Expected 2 warnings for unparsable front matter, got %d:\n%s", got, stderr.String())
+	}
+}
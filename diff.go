@@ -0,0 +1,486 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffGranularity は差分を表示する単位を表す
+type DiffGranularity int
+
+const (
+	// DiffLine は行単位のUnified diff（patchで適用可能）
+	DiffLine DiffGranularity = iota
+	// DiffWord は英数字の並びを1単語、それ以外を1文字として行内の変更箇所だけを示す
+	DiffWord
+	// DiffChar は1文字単位で行内の変更箇所だけを示す
+	DiffChar
+)
+
+// ParseDiffGranularity は "line"、"word"、"char" のいずれかの文字列から DiffGranularity を求める
+func ParseDiffGranularity(s string) (DiffGranularity, error) {
+	switch s {
+	case "line":
+		return DiffLine, nil
+	case "word":
+		return DiffWord, nil
+	case "char":
+		return DiffChar, nil
+	default:
+		return DiffLine, fmt.Errorf("unknown diff granularity %q", s)
+	}
+}
+
+// DiffOptions はUnified diffの生成方法を表す構造体
+type DiffOptions struct {
+	Context     int             // 変更箇所の前後に表示するコンテキストの行数
+	OldPrefix   string          // 変更前のファイル名に付けるプレフィックス（例: "a/"）
+	NewPrefix   string          // 変更後のファイル名に付けるプレフィックス（例: "b/"）
+	Granularity DiffGranularity // 差分を表示する単位
+	Color       bool            // 行内差分の変更箇所を端末の色で表示する（falseの場合は [-old-]{+new+} 形式）
+}
+
+// DefaultDiffOptions は patch や git apply でそのまま適用できる標準的な設定を返す
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{
+		Context:   3,
+		OldPrefix: "a/",
+		NewPrefix: "b/",
+	}
+}
+
+// diffOpKind は編集操作の種類
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffDelete
+	diffInsert
+)
+
+// diffOp は1要素分の編集操作を表す（oldIndex、newIndexはそれぞれの列上の位置）
+type diffOp struct {
+	kind     diffOpKind
+	oldIndex int
+	newIndex int
+}
+
+// myersDiff は2つの列の最短編集スクリプトをMyersのアルゴリズムで求める
+// 探索の履歴は保存せず、中央のスネークで列を分割して再帰的に求めるため、メモリ使用量は列の長さに比例する
+func myersDiff(a, b []string) []diffOp {
+	return deletesFirst(appendMyersDiff(make([]diffOp, 0, len(a)+len(b)), a, b, 0, 0))
+}
+
+// deletesFirst は連続する削除と追加の並びを、diffやgitと同じく削除を先にした順序に並べ替える
+func deletesFirst(ops []diffOp) []diffOp {
+	sorted := make([]diffOp, 0, len(ops))
+	for i := 0; i < len(ops); {
+		if ops[i].kind == diffEqual {
+			sorted = append(sorted, ops[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].kind != diffEqual {
+			j++
+		}
+		// 削除の後の追加は、削除した要素の次の位置に追加する
+		oldEnd := ops[i].oldIndex
+		for _, op := range ops[i:j] {
+			if op.kind == diffDelete {
+				sorted = append(sorted, diffOp{kind: diffDelete, oldIndex: op.oldIndex, newIndex: ops[i].newIndex})
+				oldEnd = op.oldIndex + 1
+			}
+		}
+		for _, op := range ops[i:j] {
+			if op.kind == diffInsert {
+				sorted = append(sorted, diffOp{kind: diffInsert, oldIndex: oldEnd, newIndex: op.newIndex})
+			}
+		}
+		i = j
+	}
+	return sorted
+}
+
+// appendMyersDiff は a と b の編集操作を ops に追加する
+// oldOffset、newOffset は a、b の先頭の元の列上の位置
+func appendMyersDiff(ops []diffOp, a, b []string, oldOffset, newOffset int) []diffOp {
+	// 共通の先頭・末尾は事前に取り除いてから差分を計算する
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: diffEqual, oldIndex: oldOffset + i, newIndex: newOffset + i})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	oldStart, newStart := oldOffset+prefix, newOffset+prefix
+	switch {
+	case len(midA) == 0:
+		for j := range midB {
+			ops = append(ops, diffOp{kind: diffInsert, oldIndex: oldStart, newIndex: newStart + j})
+		}
+	case len(midB) == 0:
+		for i := range midA {
+			ops = append(ops, diffOp{kind: diffDelete, oldIndex: oldStart + i, newIndex: newStart})
+		}
+	default:
+		// 中央のスネークの前後をそれぞれ再帰的に求める
+		// 先頭・末尾が一致しないため、編集距離は2以上になり、前後の列はどちらも元の列より短くなる
+		x, y, u, v := myersMiddleSnake(midA, midB)
+		ops = appendMyersDiff(ops, midA[:x], midB[:y], oldStart, newStart)
+		for i := 0; i < u-x; i++ {
+			ops = append(ops, diffOp{kind: diffEqual, oldIndex: oldStart + x + i, newIndex: newStart + y + i})
+		}
+		ops = appendMyersDiff(ops, midA[u:], midB[v:], oldStart+u, newStart+v)
+	}
+
+	for i := suffix; i > 0; i-- {
+		ops = append(ops, diffOp{kind: diffEqual, oldIndex: oldOffset + len(a) - i, newIndex: newOffset + len(b) - i})
+	}
+	return ops
+}
+
+// myersMiddleSnake は最短編集スクリプトの中央にあるスネーク（一致する要素の並び）を
+// 先頭からと末尾からの探索を同時に進めて求め、その始点 (x, y) と終点 (u, v) を返す
+// vf[k]、vb[k] はそれぞれ先頭から、末尾から対角線kの上で到達した最も遠いxを表す
+func myersMiddleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+
+	max := (n + m + 1) / 2
+	offset := max + 1
+	vf := make([]int, 2*max+3)
+	vb := make([]int, 2*max+3)
+
+	for d := 0; d <= max; d++ {
+		// 先頭からの探索
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+			// 末尾からの探索の対角線は delta-k で、1つ前のラウンドで到達した位置と重なれば中央のスネークになる
+			if kb := delta - k; odd && kb >= -(d-1) && kb <= d-1 && x+vb[offset+kb] >= n {
+				return startX, startY, x, y
+			}
+		}
+
+		// 末尾からの探索（x、yは末尾から数えた位置）
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+			if kf := delta - k; !odd && kf >= -d && kf <= d && x+vf[offset+kf] >= n {
+				return n - x, m - y, n - startX, m - startY
+			}
+		}
+	}
+	return 0, 0, 0, 0
+}
+
+// splitLines はテキストを改行を含んだ行の列に分割する
+// 最終行が改行で終わっていない場合は改行を含まない
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffHunk はUnified diffの1ハンクに含まれる編集操作の範囲を表す
+type diffHunk struct {
+	ops []diffOp
+}
+
+// groupHunks は編集操作の列をコンテキスト行を含めたハンクにまとめる
+// 変更箇所の間隔がコンテキスト行数の2倍以下の場合は1つのハンクにまとめる
+func groupHunks(ops []diffOp, context int) []diffHunk {
+	if context < 0 {
+		context = 0
+	}
+
+	var hunks []diffHunk
+	start, end := -1, -1
+	for i, op := range ops {
+		if op.kind == diffEqual {
+			continue
+		}
+		lo := i - context
+		if lo < 0 {
+			lo = 0
+		}
+		hi := i + context + 1
+		if hi > len(ops) {
+			hi = len(ops)
+		}
+		if start >= 0 && lo <= end {
+			end = hi
+			continue
+		}
+		if start >= 0 {
+			hunks = append(hunks, diffHunk{ops: ops[start:end]})
+		}
+		start, end = lo, hi
+	}
+	if start >= 0 {
+		hunks = append(hunks, diffHunk{ops: ops[start:end]})
+	}
+	return hunks
+}
+
+// header はハンクヘッダー（@@ -l,s +l,s @@）を生成する
+func (h diffHunk) header() string {
+	oldStart, newStart := h.ops[0].oldIndex, h.ops[0].newIndex
+	oldCount, newCount := 0, 0
+	for _, op := range h.ops {
+		if op.kind != diffInsert {
+			oldCount++
+		}
+		if op.kind != diffDelete {
+			newCount++
+		}
+	}
+	return fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+}
+
+// hunkRange はGNU diffと同じ規則でハンクの範囲を表す文字列を生成する
+// 行数が0の場合は直前の行番号を、1の場合は行数を省略する
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+// unifiedDiff は2つのテキストのUnified diffを生成する
+func unifiedDiff(original, modified, oldName, newName string, context int) string {
+	a := splitLines(original)
+	b := splitLines(modified)
+	hunks := groupHunks(myersDiff(a, b), context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var diff strings.Builder
+	diff.WriteString(fmt.Sprintf("--- %s\n", oldName))
+	diff.WriteString(fmt.Sprintf("+++ %s\n", newName))
+
+	for _, hunk := range hunks {
+		diff.WriteString(hunk.header())
+		for _, op := range hunk.ops {
+			switch op.kind {
+			case diffEqual:
+				writeDiffLine(&diff, ' ', a[op.oldIndex])
+			case diffDelete:
+				writeDiffLine(&diff, '-', a[op.oldIndex])
+			case diffInsert:
+				writeDiffLine(&diff, '+', b[op.newIndex])
+			}
+		}
+	}
+	return diff.String()
+}
+
+// writeDiffLine は1行分の差分を書き込む
+// 改行で終わらない行にはpatchが解釈できる「\ No newline at end of file」を付ける
+func writeDiffLine(diff *strings.Builder, mark byte, line string) {
+	diff.WriteByte(mark)
+	diff.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		diff.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// 行内差分の色付け用のエスケープシーケンス
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// inlineRenderer は行内差分の削除箇所と追加箇所の表示方法を表す
+type inlineRenderer interface {
+	header(line string) string
+	deleted(text string) string
+	inserted(text string) string
+}
+
+// plainRenderer は git diff --word-diff=plain と同じ [-old-]{+new+} 形式で表示する
+type plainRenderer struct{}
+
+func (plainRenderer) header(line string) string   { return line }
+func (plainRenderer) deleted(text string) string  { return "[-" + text + "-]" }
+func (plainRenderer) inserted(text string) string { return "{+" + text + "+}" }
+
+// colorRenderer は削除箇所を赤、追加箇所を緑の端末の色で表示する
+type colorRenderer struct{}
+
+func (colorRenderer) header(line string) string {
+	if strings.HasPrefix(line, "@@") {
+		return colorCyan + strings.TrimSuffix(line, "\n") + colorReset + "\n"
+	}
+	return colorBold + strings.TrimSuffix(line, "\n") + colorReset + "\n"
+}
+func (colorRenderer) deleted(text string) string  { return colorRed + text + colorReset }
+func (colorRenderer) inserted(text string) string { return colorGreen + text + colorReset }
+
+// tokenize は行内差分を計算するためにテキストを単位ごとに分割する
+func tokenize(text string, granularity DiffGranularity) []string {
+	var tokens []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i + 1
+		if granularity == DiffWord && isWordRune(runes[i]) {
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+		}
+		tokens = append(tokens, string(runes[i:j]))
+		i = j
+	}
+	return tokens
+}
+
+// isWordRune は単語の一部として扱う半角英数字かどうかを判定する
+func isWordRune(c rune) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// inlineDiff は2つのテキストの行内差分を生成する
+// 行単位でハンクを求めたあと、変更された行のまとまりごとに指定した単位で差分を取り、変更箇所だけを強調する
+func inlineDiff(original, modified, oldName, newName string, opts DiffOptions) string {
+	a := splitLines(original)
+	b := splitLines(modified)
+	hunks := groupHunks(myersDiff(a, b), opts.Context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var renderer inlineRenderer = plainRenderer{}
+	if opts.Color {
+		renderer = colorRenderer{}
+	}
+
+	var diff strings.Builder
+	diff.WriteString(renderer.header(fmt.Sprintf("--- %s\n", oldName)))
+	diff.WriteString(renderer.header(fmt.Sprintf("+++ %s\n", newName)))
+
+	for _, hunk := range hunks {
+		diff.WriteString(renderer.header(hunk.header()))
+
+		var oldBlock, newBlock strings.Builder
+		flush := func() {
+			if oldBlock.Len() == 0 && newBlock.Len() == 0 {
+				return
+			}
+			diff.WriteString(renderInline(oldBlock.String(), newBlock.String(), opts.Granularity, renderer))
+			oldBlock.Reset()
+			newBlock.Reset()
+		}
+
+		for _, op := range hunk.ops {
+			switch op.kind {
+			case diffEqual:
+				flush()
+				diff.WriteString(ensureNewline(a[op.oldIndex]))
+			case diffDelete:
+				oldBlock.WriteString(ensureNewline(a[op.oldIndex]))
+			case diffInsert:
+				newBlock.WriteString(ensureNewline(b[op.newIndex]))
+			}
+		}
+		flush()
+	}
+	return diff.String()
+}
+
+// ensureNewline は改行で終わらない最終行に改行を補う
+func ensureNewline(line string) string {
+	if strings.HasSuffix(line, "\n") {
+		return line
+	}
+	return line + "\n"
+}
+
+// renderInline は変更前後のテキストの差分を指定した単位で求め、変更箇所を強調したテキストを返す
+func renderInline(oldText, newText string, granularity DiffGranularity, renderer inlineRenderer) string {
+	oldTokens := tokenize(oldText, granularity)
+	newTokens := tokenize(newText, granularity)
+
+	var out, deleted, inserted strings.Builder
+	flush := func() {
+		if deleted.Len() > 0 {
+			out.WriteString(renderer.deleted(deleted.String()))
+			deleted.Reset()
+		}
+		if inserted.Len() > 0 {
+			out.WriteString(renderer.inserted(inserted.String()))
+			inserted.Reset()
+		}
+	}
+
+	for _, op := range myersDiff(oldTokens, newTokens) {
+		switch op.kind {
+		case diffEqual:
+			flush()
+			out.WriteString(oldTokens[op.oldIndex])
+		case diffDelete:
+			deleted.WriteString(oldTokens[op.oldIndex])
+		case diffInsert:
+			inserted.WriteString(newTokens[op.newIndex])
+		}
+	}
+	flush()
+	return out.String()
+}
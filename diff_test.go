@@ -0,0 +1,274 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		modified string
+		context  int
+		expected string
+	}{
+		{
+			name:     "no changes",
+			original: "a\nb\n",
+			modified: "a\nb\n",
+			context:  3,
+			expected: "",
+		},
+		{
+			name:     "single line change",
+			original: "a\nb\nc\n",
+			modified: "a\nB\nc\n",
+			context:  3,
+			expected: "--- a/x.md\n+++ b/x.md\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:     "empty lines are kept as context",
+			original: "a\n\nb\n\nc\n",
+			modified: "a\n\nB\n\nc\n",
+			context:  1,
+			expected: "--- a/x.md\n+++ b/x.md\n@@ -2,3 +2,3 @@\n \n-b\n+B\n \n",
+		},
+		{
+			name:     "line count changes",
+			original: "a\nb c\nd\n",
+			modified: "a\nb\nc\nd\n",
+			context:  1,
+			expected: "--- a/x.md\n+++ b/x.md\n@@ -1,3 +1,4 @@\n a\n-b c\n+b\n+c\n d\n",
+		},
+		{
+			name:     "separate hunks",
+			original: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			modified: "X\n2\n3\n4\n5\n6\n7\nY\n",
+			context:  1,
+			expected: "--- a/x.md\n+++ b/x.md\n@@ -1,2 +1,2 @@\n-1\n+X\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+Y\n",
+		},
+		{
+			name:     "close changes are merged into one hunk",
+			original: "1\n2\n3\n4\n5\n",
+			modified: "X\n2\n3\n4\nY\n",
+			context:  2,
+			expected: "--- a/x.md\n+++ b/x.md\n@@ -1,5 +1,5 @@\n-1\n+X\n 2\n 3\n 4\n-5\n+Y\n",
+		},
+		{
+			name:     "zero context",
+			original: "a\nb\nc\n",
+			modified: "a\nc\n",
+			context:  0,
+			expected: "--- a/x.md\n+++ b/x.md\n@@ -2 +1,0 @@\n-b\n",
+		},
+		{
+			name:     "no newline at end of file",
+			original: "a\nb",
+			modified: "a\nB",
+			context:  3,
+			expected: "--- a/x.md\n+++ b/x.md\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+B\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff(tt.original, tt.modified, "a/x.md", "b/x.md", tt.context)
+			if got != tt.expected {
+				t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMyersDiff(t *testing.T) {
+	a := []string{"a", "b", "c", "a", "b", "b", "a"}
+	b := []string{"c", "b", "a", "b", "a", "c"}
+
+	ops := myersDiff(a, b)
+
+	// 編集操作を適用するとbが得られることを確認する
+	var rebuilt []string
+	edits := 0
+	for _, op := range ops {
+		switch op.kind {
+		case diffEqual:
+			if a[op.oldIndex] != b[op.newIndex] {
+				t.Fatalf("equal op mismatch: %q != %q", a[op.oldIndex], b[op.newIndex])
+			}
+			rebuilt = append(rebuilt, a[op.oldIndex])
+		case diffInsert:
+			rebuilt = append(rebuilt, b[op.newIndex])
+			edits++
+		case diffDelete:
+			edits++
+		}
+	}
+
+	if len(rebuilt) != len(b) {
+		t.Fatalf("rebuilt = %v, want %v", rebuilt, b)
+	}
+	for i := range b {
+		if rebuilt[i] != b[i] {
+			t.Fatalf("rebuilt = %v, want %v", rebuilt, b)
+		}
+	}
+
+	// 最短編集距離は5
+	if edits != 5 {
+		t.Errorf("edit distance = %d, want 5", edits)
+	}
+}
+
+func TestMyersDiff_Random(t *testing.T) {
+	// 中央のスネークで分割して求めた編集操作が、最長共通部分列から求めた最短編集距離と一致することを確認する
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "b", "c"}
+	for n := 0; n < 200; n++ {
+		a := make([]string, rng.Intn(20))
+		for i := range a {
+			a[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		b := make([]string, rng.Intn(20))
+		for i := range b {
+			b[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+
+		var rebuilt []string
+		edits, oldIndex, newIndex := 0, 0, 0
+		for _, op := range myersDiff(a, b) {
+			if op.oldIndex != oldIndex || op.newIndex != newIndex {
+				t.Fatalf("a = %v, b = %v: op %+v, want position (%d, %d)", a, b, op, oldIndex, newIndex)
+			}
+			switch op.kind {
+			case diffEqual:
+				if a[op.oldIndex] != b[op.newIndex] {
+					t.Fatalf("a = %v, b = %v: equal op mismatch: %q != %q", a, b, a[op.oldIndex], b[op.newIndex])
+				}
+				rebuilt = append(rebuilt, a[op.oldIndex])
+				oldIndex++
+				newIndex++
+			case diffDelete:
+				edits++
+				oldIndex++
+			case diffInsert:
+				rebuilt = append(rebuilt, b[op.newIndex])
+				edits++
+				newIndex++
+			}
+		}
+		if oldIndex != len(a) || len(rebuilt) != len(b) {
+			t.Fatalf("a = %v, b = %v: rebuilt = %v", a, b, rebuilt)
+		}
+		for i := range b {
+			if rebuilt[i] != b[i] {
+				t.Fatalf("a = %v, b = %v: rebuilt = %v", a, b, rebuilt)
+			}
+		}
+
+		// 最長共通部分列の長さを動的計画法で求める
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		if want := len(a) + len(b) - 2*lcs[0][0]; edits != want {
+			t.Errorf("a = %v, b = %v: edit distance = %d, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestInlineDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		modified string
+		opts     DiffOptions
+		expected string
+	}{
+		{
+			name:     "char granularity on a long Japanese line",
+			original: "前\nこれはハードウエアとcookieの話です。\n後\n",
+			modified: "前\nこれはハードウェアとCookieの話です。\n後\n",
+			opts:     DiffOptions{Context: 1, Granularity: DiffChar},
+			expected: "--- x.md\n+++ x.md\n@@ -1,3 +1,3 @@\n前\nこれはハードウ[-エ-]{+ェ+}アと[-c-]{+C+}ookieの話です。\n後\n",
+		},
+		{
+			name:     "word granularity treats alphanumerics as one token",
+			original: "これはcookieの話です。\n",
+			modified: "これはCookieの話です。\n",
+			opts:     DiffOptions{Context: 0, Granularity: DiffWord},
+			expected: "--- x.md\n+++ x.md\n@@ -1 +1 @@\nこれは[-cookie-]{+Cookie+}の話です。\n",
+		},
+		{
+			name:     "color renderer",
+			original: "abc\n",
+			modified: "abd\n",
+			opts:     DiffOptions{Context: 0, Granularity: DiffChar, Color: true},
+			expected: "\x1b[1m--- x.md\x1b[0m\n\x1b[1m+++ x.md\x1b[0m\n\x1b[36m@@ -1 +1 @@\x1b[0m\nab\x1b[31mc\x1b[0m\x1b[32md\x1b[0m\n",
+		},
+		{
+			name:     "no changes",
+			original: "abc\n",
+			modified: "abc\n",
+			opts:     DiffOptions{Context: 3, Granularity: DiffChar},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inlineDiff(tt.original, tt.modified, "x.md", "x.md", tt.opts)
+			if got != tt.expected {
+				t.Errorf("inlineDiff() =\n%q\nwant\n%q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDiffGranularity(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    DiffGranularity
+		wantErr bool
+	}{
+		{input: "line", want: DiffLine},
+		{input: "word", want: DiffWord},
+		{input: "char", want: DiffChar},
+		{input: "byte", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDiffGranularity(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDiffGranularity(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDiffGranularity(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fileSettingsKey はフロントマターで FileSettings を書くキー
+const fileSettingsKey = "grh"
+
+// FileSettings は文書のフロントマターの grh に書く、その文書だけに適用するルールの設定
+//
+//	---
+//	title: 記事のタイトル
+//	grh:
+//	  disable: [rule-3f2a9c1e, /サーバ/]  # この文書では適用しないルール
+//	  enable: [cookie-katakana]          # disabled: true のルールのうち、この文書では適用するもの
+//	  rules: extra.yml                   # この文書だけに追加するルールファイル
+//	---
+//
+// disable と enable の要素は ignoreRules と同じ形式で書ける
+type FileSettings struct {
+	Disable []IgnoreRule `yaml:"disable,omitempty" json:"disable,omitempty"`
+	Enable  []IgnoreRule `yaml:"enable,omitempty" json:"enable,omitempty"`
+	Rules   string       `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// ErrInvalidFrontMatter は文書のフロントマターを解析できないことを表す
+// --- で始まるMarkdownの区切り線をフロントマターとみなした場合などに発生する
+var ErrInvalidFrontMatter = errors.New("failed to parse front matter")
+
+// ReadFileSettings は文書のフロントマターから grh の設定を読み込む
+// フロントマターがない場合や、フロントマターに grh の設定がない場合は nil を返す
+// フロントマターを解析できない場合は ErrInvalidFrontMatter を、grh の設定が正しくない場合はそれ以外のエラーを返す
+func ReadFileSettings(text string) (*FileSettings, error) {
+	fm, ok := findFrontMatter(text)
+	if !ok {
+		return nil, nil
+	}
+
+	body := []byte(text[fm.body.Start:fm.body.End])
+	var values map[string]interface{}
+	var err error
+	switch fm.format {
+	case frontMatterYAML:
+		err = yaml.Unmarshal(body, &values)
+	case frontMatterTOML:
+		err = toml.Unmarshal(body, &values)
+	case frontMatterJSON:
+		err = json.Unmarshal(body, &values)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w (%s): %w", ErrInvalidFrontMatter, fm.format, err)
+	}
+
+	value, ok := values[fileSettingsKey]
+	if !ok {
+		return nil, nil
+	}
+
+	// 形式によらず同じ方法で読み込むため、YAMLに変換してから読み込む
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s in front matter: %w", fileSettingsKey, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var settings FileSettings
+	if err := decoder.Decode(&settings); err != nil {
+		return nil, fmt.Errorf("invalid %s in front matter: %w", fileSettingsKey, err)
+	}
+	return &settings, nil
+}
+
+// WithFileSettings は文書のフロントマターの grh の設定を適用した新しいConfigを返す（c は変更しない）
+// rules に書いたルールファイルは baseDir からの相対パスとして読み込み、ルールだけを c のルールとマージする。
+// markdown や frontMatterFields などのルール以外の設定は c のものを使う。
+// enable に該当するルールは disabled: true であっても適用し、disable に該当するルールは除く（両方に該当する場合は除く）
+func (c *Config) WithFileSettings(settings *FileSettings, baseDir string) (*Config, error) {
+	return c.withFileSettings(settings, baseDir, LoadConfigWithImports)
+}
+
+// withFileSettings は WithFileSettings と同じだが、rules に書いたルールファイルを load で読み込む
+func (c *Config) withFileSettings(settings *FileSettings, baseDir string, load func(path string) (*Config, error)) (*Config, error) {
+	enable, err := compileSelectors(settings.Enable)
+	if err != nil {
+		return nil, fmt.Errorf("invalid enable in front matter: %w", err)
+	}
+	disable, err := compileSelectors(settings.Disable)
+	if err != nil {
+		return nil, fmt.Errorf("invalid disable in front matter: %w", err)
+	}
+
+	config := *c
+	config.Rules = slices.Clone(c.Rules)
+	if settings.Rules != "" {
+		path := settings.Rules
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		extra, err := load(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rules in front matter: %w", err)
+		}
+		config.Rules = MergeConfigs(c, extra).Rules
+	}
+
+	rules := []Rule{}
+	for _, rule := range config.Rules {
+		if disable.matches(&rule) {
+			continue
+		}
+		if enable.matches(&rule) {
+			rule.Disabled = false
+		}
+		rules = append(rules, rule)
+	}
+	config.Rules = rules
+	return &config, nil
+}
+
+// EnabledRules は適用するルール（disabled: true のものを除く）を「ID（expected）」の形式で返す
+// 文書ごとに実際に適用されるルールをログに出力するために使う
+func (c *Config) EnabledRules() []string {
+	var rules []string
+	for i := range c.Rules {
+		if !c.Rules[i].Disabled {
+			rules = append(rules, fmt.Sprintf("%s (%s)", c.Rules[i].id(), c.Rules[i].Expected))
+		}
+	}
+	return rules
+}
+
+// ForDocument は文書のフロントマターに grh の設定がある場合に、それを適用したReplacerを返す
+// 設定がない場合は r をそのまま返す。baseDir は設定の rules に書いたルールファイルを探す基準のディレクトリ
+// フロントマターを解析できない場合は ReadFileSettings と同じく ErrInvalidFrontMatter を返す
+func (r *Replacer) ForDocument(text, baseDir string) (*Replacer, error) {
+	settings, err := ReadFileSettings(text)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return r, nil
+	}
+
+	config, err := r.config.withFileSettings(settings, baseDir, r.rulesCache.load)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Info("Applied front matter settings",
+		"disable", settings.Disable,
+		"enable", settings.Enable,
+		"rules", settings.Rules,
+		"rules_count", len(config.Rules))
+	r.logger.Debug("Effective rules", "rules", config.EnabledRules())
+
+	return &Replacer{config: config, logger: r.logger, rulesCache: r.rulesCache}, nil
+}
+
+// rulesCache はフロントマターの rules に書いたルールファイルを読み込んだ結果を、ファイルの絶対パスごとに保持する
+// 同じルールファイルを指定した文書ごとに読み込み直さないために使う。複数のgoroutineから使える
+type rulesCache struct {
+	mu      sync.Mutex
+	configs map[string]*Config
+}
+
+// newRulesCache は空の rulesCache を作成する
+func newRulesCache() *rulesCache {
+	return &rulesCache{configs: make(map[string]*Config)}
+}
+
+// load は path のルールファイルを読み込む（読み込み済みの場合はその結果を返す）
+// 読み込みに失敗した場合は結果を保持しない
+func (rc *rulesCache) load(path string) (*Config, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %q: %w", path, err)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if config, ok := rc.configs[abs]; ok {
+		return config, nil
+	}
+	config, err := LoadConfigWithImports(abs)
+	if err != nil {
+		return nil, err
+	}
+	rc.configs[abs] = config
+	return config, nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadFileSettings(t *testing.T) {
+	want := &FileSettings{
+		Disable: []IgnoreRule{{ID: "cookie"}, {Pattern: "/サーバ/"}},
+		Enable:  []IgnoreRule{{Expected: "jQuery"}},
+		Rules:   "extra.yml",
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    *FileSettings
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			input: "---\ntitle: 記事\n" +
+				"grh: {disable: [cookie, /サーバ/], enable: [{expected: jQuery}], rules: extra.yml}\n" +
+				"---\n本文\n",
+			want: want,
+		},
+		{
+			name: "toml",
+			input: "+++\ntitle = '記事'\n\n" +
+				"[grh]\ndisable = ['cookie', '/サーバ/']\nenable = [{expected = 'jQuery'}]\nrules = 'extra.yml'\n" +
+				"+++\n本文\n",
+			want: want,
+		},
+		{
+			name: "json",
+			input: "{\n  \"title\": \"記事\",\n" +
+				"  \"grh\": {\"disable\": [\"cookie\", \"/サーバ/\"], \"enable\": [{\"expected\": \"jQuery\"}], \"rules\": \"extra.yml\"}\n" +
+				"}\n本文\n",
+			want: want,
+		},
+		{name: "no settings", input: "---\ntitle: 記事\n---\n本文\n"},
+		{name: "no front matter", input: "本文\n"},
+		{name: "unknown field", input: "---\ngrh: {disabled: [cookie]}\n---\n", wantErr: "field disabled not found"},
+		{name: "invalid front matter", input: "---\ntitle: [\n---\n", wantErr: "failed to parse front matter (yaml)"},
+		{name: "thematic break", input: "---\nIntro cookie text\n---\n\ncookie body\n", wantErr: "failed to parse front matter (yaml)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFileSettings(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadFileSettings() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadFileSettings() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadFileSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplacer_ForDocument(t *testing.T) {
+	dir := t.TempDir()
+	extra := "version: 1\nrules:\n  - id: server\n    expected: サーバ\n    pattern: サーバー\n"
+	if err := os.WriteFile(filepath.Join(dir, "extra.yml"), []byte(extra), 0644); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+
+	config := &Config{Rules: []Rule{
+		{ID: "cookie", Expected: "Cookie"},
+		{ID: "jquery", Expected: "jQuery", Pattern: "jquery"},
+		{ID: "javascript", Expected: "JavaScript", Pattern: "javascript", Disabled: true},
+	}}
+	for i := range config.Rules {
+		if err := config.Rules[i].CompilePattern(); err != nil {
+			t.Fatalf("Failed to compile rule %d: %v", i, err)
+		}
+	}
+	body := "cookie と jquery と javascript のサーバー\n"
+
+	tests := []struct {
+		name        string
+		frontMatter string
+		expected    string
+		rules       []string
+		wantErr     string
+	}{
+		{
+			name:     "no settings",
+			expected: "Cookie と jQuery と javascript のサーバー\n",
+			rules:    []string{"cookie (Cookie)", "jquery (jQuery)"},
+		},
+		{
+			name:        "disable and enable",
+			frontMatter: "---\ngrh:\n  disable: [cookie]\n  enable: [javascript]\n---\n",
+			expected:    "cookie と jQuery と JavaScript のサーバー\n",
+			rules:       []string{"jquery (jQuery)", "javascript (JavaScript)"},
+		},
+		{
+			name:        "disable wins over enable",
+			frontMatter: "---\ngrh:\n  disable: [{expected: /Script/}]\n  enable: [javascript]\n---\n",
+			expected:    "Cookie と jQuery と javascript のサーバー\n",
+			rules:       []string{"cookie (Cookie)", "jquery (jQuery)"},
+		},
+		{
+			name:        "extra rules",
+			frontMatter: "+++\n[grh]\nrules = 'extra.yml'\n+++\n",
+			expected:    "Cookie と jQuery と javascript のサーバ\n",
+			rules:       []string{"cookie (Cookie)", "jquery (jQuery)", "server (サーバ)"},
+		},
+		{
+			name:        "missing rules",
+			frontMatter: "---\ngrh: {rules: missing.yml}\n---\n",
+			wantErr:     "failed to load rules in front matter",
+		},
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	replacer := NewReplacerWithLogger(config, logger)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tt.frontMatter + body
+			doc, err := replacer.ForDocument(input, dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ForDocument() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ForDocument() error = %v", err)
+			}
+
+			if got := doc.ReplaceString(input).Result; got != tt.frontMatter+tt.expected {
+				t.Errorf("ReplaceString() = %q, want %q", got, tt.frontMatter+tt.expected)
+			}
+			if got := doc.config.EnabledRules(); !reflect.DeepEqual(got, tt.rules) {
+				t.Errorf("EnabledRules() = %q, want %q", got, tt.rules)
+			}
+		})
+	}
+
+	// 元のReplacerのルールは変更しない
+	if !config.Rules[2].Disabled || len(config.Rules) != 3 {
+		t.Errorf("ForDocument() modified the original config: %+v", config.Rules)
+	}
+}
+
+func TestReplacer_ForDocument_ExtraRulesSettings(t *testing.T) {
+	dir := t.TempDir()
+	extraPath := filepath.Join(dir, "extra.yml")
+	extra := "version: 1\nmarkdown: goldmark\nfrontMatterFields: [summary]\nrules:\n  - id: server\n    expected: サーバ\n    pattern: サーバー\n"
+	if err := os.WriteFile(extraPath, []byte(extra), 0644); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+
+	config := &Config{Markdown: MarkdownRegexp, FrontMatterFields: []string{"title"}}
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	replacer := NewReplacerWithLogger(config, logger)
+	input := "---\ntitle: サーバー\nsummary: サーバー\ngrh: {rules: extra.yml}\n---\nサーバー\n"
+
+	doc, err := replacer.ForDocument(input, dir)
+	if err != nil {
+		t.Fatalf("ForDocument() error = %v", err)
+	}
+	// ルールファイルの markdown と frontMatterFields ではなく、元の設定を使う
+	if doc.config.Markdown != MarkdownRegexp || !reflect.DeepEqual(doc.config.FrontMatterFields, []string{"title"}) {
+		t.Errorf("ForDocument() settings = %q, %q, want the original settings", doc.config.Markdown, doc.config.FrontMatterFields)
+	}
+	want := "---\ntitle: サーバ\nsummary: サーバー\ngrh: {rules: extra.yml}\n---\nサーバ\n"
+	if got := doc.ReplaceString(input).Result; got != want {
+		t.Errorf("ReplaceString() = %q, want %q", got, want)
+	}
+
+	// 同じルールファイルは読み込み直さない
+	if err := os.Remove(extraPath); err != nil {
+		t.Fatalf("Failed to remove rules: %v", err)
+	}
+	if _, err := doc.ForDocument(input, dir); err != nil {
+		t.Errorf("ForDocument() error = %v, want the cached rules", err)
+	}
+}
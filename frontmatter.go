@@ -0,0 +1,355 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// フロントマターの形式
+const (
+	frontMatterYAML = "yaml" // --- で囲む
+	frontMatterTOML = "toml" // +++ で囲む
+	frontMatterJSON = "json" // { で始まり } で終わるJSONオブジェクト
+)
+
+// frontMatter は文書の先頭にあるHugoのフロントマターを表す
+type frontMatter struct {
+	format string
+	span   TextSpan // 区切りを含むフロントマター全体の範囲
+	body   TextSpan // 区切りを除いた中身の範囲（JSONの場合は span と同じ）
+}
+
+// findFrontMatter は文書の先頭にあるフロントマターを探す
+func findFrontMatter(text string) (frontMatter, bool) {
+	for _, d := range []struct {
+		delimiter string
+		format    string
+	}{
+		{"---", frontMatterYAML},
+		{"+++", frontMatterTOML},
+	} {
+		if fm, ok := findDelimitedFrontMatter(text, d.delimiter, d.format); ok {
+			return fm, true
+		}
+	}
+
+	if strings.HasPrefix(text, "{") {
+		// 先頭のJSONオブジェクト1つをフロントマターとする
+		dec := json.NewDecoder(strings.NewReader(text))
+		var v map[string]interface{}
+		if err := dec.Decode(&v); err == nil {
+			end := int(dec.InputOffset())
+			return frontMatter{format: frontMatterJSON, span: TextSpan{0, end}, body: TextSpan{0, end}}, true
+		}
+	}
+
+	return frontMatter{}, false
+}
+
+// findDelimitedFrontMatter は delimiter だけの行で囲まれたフロントマターを探す
+func findDelimitedFrontMatter(text, delimiter, format string) (frontMatter, bool) {
+	firstLine, rest, ok := strings.Cut(text, "\n")
+	if !ok || strings.TrimRight(firstLine, " \t\r") != delimiter {
+		return frontMatter{}, false
+	}
+
+	bodyStart := len(firstLine) + 1
+	offset := bodyStart
+	for _, line := range strings.SplitAfter(rest, "\n") {
+		if strings.TrimRight(line, " \t\r\n") == delimiter {
+			return frontMatter{
+				format: format,
+				span:   TextSpan{0, offset + len(line)},
+				body:   TextSpan{bodyStart, offset},
+			}, true
+		}
+		offset += len(line)
+	}
+	return frontMatter{}, false
+}
+
+// blankFrontMatter はフロントマターの改行以外の文字を空白に置き換える
+// Markdownとして解析する際にフロントマターを見出しや段落として扱わないようにするために使う（位置は変わらない）
+func blankFrontMatter(text string, fm frontMatter) string {
+	b := []byte(text)
+	for i := fm.span.Start; i < fm.span.End; i++ {
+		if b[i] != '\n' {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// fieldValueSpans はフロントマターの fields に指定したトップレベルのキーの文字列の値の範囲を返す
+// 値が文字列の配列の場合は各要素の範囲を返す。置換しても書式が崩れないよう、
+// 書かれたままの文字列と値が一致するもの（エスケープを含まない1行の文字列）だけを対象にする
+func (fm frontMatter) fieldValueSpans(text string, fields []string) []TextSpan {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var spans []TextSpan
+	switch fm.format {
+	case frontMatterYAML:
+		spans = yamlFieldValueSpans(text, fm.body, fields)
+	case frontMatterTOML:
+		spans = tomlFieldValueSpans(text, fm.body, fields)
+	case frontMatterJSON:
+		spans = jsonFieldValueSpans(text, fm.body, fields)
+	}
+	return mergeSpans(spans)
+}
+
+// yamlFieldValueSpans はYAMLのフロントマターから値の範囲を求める
+func yamlFieldValueSpans(text string, body TextSpan, fields []string) []TextSpan {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text[body.Start:body.End]), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	mapping := doc.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	lines := newLineIndex(text)
+	firstLine := lines.lineOf(body.Start)
+	var spans []TextSpan
+	addScalar := func(n *yaml.Node) {
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!str" || strings.Contains(n.Value, "\n") {
+			return
+		}
+		start, ok := lines.offsetOf(firstLine+n.Line-1, n.Column)
+		if !ok {
+			return
+		}
+		if quote := text[start]; n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+			if !strings.HasPrefix(text[start+1:], n.Value+string(quote)) {
+				return
+			}
+			start++
+		} else if !strings.HasPrefix(text[start:], n.Value) {
+			return
+		}
+		spans = append(spans, TextSpan{Start: start, End: start + len(n.Value)})
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if !slices.Contains(fields, mapping.Content[i].Value) {
+			continue
+		}
+		value := mapping.Content[i+1]
+		if value.Kind == yaml.SequenceNode {
+			for _, item := range value.Content {
+				addScalar(item)
+			}
+			continue
+		}
+		addScalar(value)
+	}
+	return spans
+}
+
+// tomlKeyValueRegex はTOMLのトップレベルの `key = 値` の行のパターン
+var tomlKeyValueRegex = regexp.MustCompile(`^[ \t]*([A-Za-z0-9_-]+|"[^"\n]*")[ \t]*=[ \t]*(.*)$`)
+
+// tomlStringRegex はTOMLの1行の文字列（エスケープを含まない基本文字列とリテラル文字列）のパターン
+var tomlStringRegex = regexp.MustCompile(`^(?:"([^"\\\n]*)"|'([^'\n]*)')`)
+
+// tomlFieldValueSpans はTOMLのフロントマターから値の範囲を求める
+// 最初のテーブル（[params] など）より前にある、1行で書かれた文字列と文字列の配列だけを対象にする
+// 三重引用符で囲んだ複数行の文字列の中の行は、key = 値 の形でも項目とはみなさない
+func tomlFieldValueSpans(text string, body TextSpan, fields []string) []TextSpan {
+	var spans []TextSpan
+	offset := body.Start
+	open := "" // 閉じていない複数行の文字列の区切り
+	for _, line := range strings.SplitAfter(text[body.Start:body.End], "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		content := strings.TrimRight(line, "\r\n")
+		if open != "" {
+			open = tomlOpenMultiline(content, open)
+			continue
+		}
+		open = tomlOpenMultiline(content, "")
+
+		if strings.HasPrefix(strings.TrimSpace(content), "[") {
+			break
+		}
+		m := tomlKeyValueRegex.FindStringSubmatchIndex(content)
+		if m == nil || !slices.Contains(fields, strings.Trim(content[m[2]:m[3]], `"`)) {
+			continue
+		}
+		if value := content[m[4]:]; strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, "'''") {
+			continue
+		}
+
+		// 配列の場合は "[" の後から、文字列と区切りの "," が続く間だけ読む
+		pos := m[4]
+		inArray := strings.HasPrefix(content[pos:], "[")
+		if inArray {
+			pos++
+		}
+		for {
+			if inArray {
+				pos += len(content[pos:]) - len(strings.TrimLeft(content[pos:], " \t,"))
+			}
+			sm := tomlStringRegex.FindStringSubmatchIndex(content[pos:])
+			if sm == nil {
+				break
+			}
+			group := 2
+			if sm[group] < 0 {
+				group = 4
+			}
+			spans = append(spans, TextSpan{Start: lineStart + pos + sm[group], End: lineStart + pos + sm[group+1]})
+			pos += sm[1]
+			if !inArray {
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// tomlOpenMultiline は TOML の1行を読み、行末で閉じていない複数行の文字列の区切り（三重引用符）を返す
+// open は行の先頭で閉じていない区切り。1行の文字列の中の引用符とコメントは区切りとみなさない
+func tomlOpenMultiline(line, open string) string {
+	for i := 0; i < len(line); {
+		rest := line[i:]
+		switch {
+		case open != "":
+			end := strings.Index(rest, open)
+			if end < 0 {
+				return open
+			}
+			// 基本文字列の \" は区切りではない
+			if open == `"""` && end > 0 && rest[end-1] == '\\' {
+				i += end + 1
+				continue
+			}
+			i += end + len(open)
+			open = ""
+		case strings.HasPrefix(rest, `"""`), strings.HasPrefix(rest, "'''"):
+			open = rest[:3]
+			i += 3
+		case rest[0] == '"' || rest[0] == '\'':
+			// 1行の文字列は閉じる引用符まで読み飛ばす
+			end := 1
+			for end < len(rest) && rest[end] != rest[0] {
+				if rest[0] == '"' && rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			i += end + 1
+		case rest[0] == '#':
+			return ""
+		default:
+			i++
+		}
+	}
+	return open
+}
+
+// jsonFieldValueSpans はJSONのフロントマターから値の範囲を求める
+func jsonFieldValueSpans(text string, body TextSpan, fields []string) []TextSpan {
+	source := text[body.Start:body.End]
+	dec := json.NewDecoder(strings.NewReader(source))
+
+	var spans []TextSpan
+	// addString は直前に読んだ文字列のトークンが、エスケープを含まずに書かれている場合に範囲を加える
+	addString := func(value string) {
+		end := int(dec.InputOffset())
+		start := end - len(value) - 2
+		if start >= 0 && source[start:end] == `"`+value+`"` {
+			spans = append(spans, TextSpan{Start: body.Start + start + 1, End: body.Start + end - 1})
+		}
+	}
+
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return spans
+		}
+		value, err := dec.Token()
+		if err != nil {
+			return spans
+		}
+		if name, _ := key.(string); !slices.Contains(fields, name) {
+			if err := skipJSONValue(dec, value); err != nil {
+				return spans
+			}
+			continue
+		}
+
+		// 値が文字列または文字列の配列の場合に範囲を加える
+		switch v := value.(type) {
+		case string:
+			addString(v)
+		case json.Delim:
+			if v != '[' {
+				if err := skipJSONValue(dec, value); err != nil {
+					return spans
+				}
+				continue
+			}
+			for dec.More() {
+				item, err := dec.Token()
+				if err != nil {
+					return spans
+				}
+				if s, ok := item.(string); ok {
+					addString(s)
+				} else if err := skipJSONValue(dec, item); err != nil {
+					return spans
+				}
+			}
+			if _, err := dec.Token(); err != nil {
+				return spans
+			}
+		}
+	}
+	return spans
+}
+
+// skipJSONValue は読み込んだトークンがオブジェクトや配列の開始の場合に、その終わりまで読み飛ばす
+func skipJSONValue(dec *json.Decoder, token json.Token) error {
+	if d, ok := token.(json.Delim); !ok || d == '}' || d == ']' {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFindFrontMatter(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		format string
+		found  string
+	}{
+		{name: "yaml", input: "---\ntitle: a\n---\n本文\n", format: frontMatterYAML, found: "---\ntitle: a\n---\n"},
+		{name: "toml", input: "+++\ntitle = 'a'\n+++\n本文\n", format: frontMatterTOML, found: "+++\ntitle = 'a'\n+++\n"},
+		{name: "json", input: "{\n  \"title\": \"a\"\n}\n本文\n", format: frontMatterJSON, found: "{\n  \"title\": \"a\"\n}"},
+		{name: "not closed", input: "---\ntitle: a\n本文\n"},
+		{name: "not at the beginning", input: "本文\n---\ntitle: a\n---\n"},
+		{name: "shortcode", input: "{{< note >}}本文{{< /note >}}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, ok := findFrontMatter(tt.input)
+			if ok != (tt.format != "") {
+				t.Fatalf("findFrontMatter() found = %v, want %v", ok, tt.format != "")
+			}
+			if !ok {
+				return
+			}
+			if fm.format != tt.format {
+				t.Errorf("format = %q, want %q", fm.format, tt.format)
+			}
+			if got := tt.input[fm.span.Start:fm.span.End]; got != tt.found {
+				t.Errorf("front matter = %q, want %q", got, tt.found)
+			}
+		})
+	}
+}
+
+func TestFrontMatter_FieldValueSpans(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name: "yaml",
+			input: "---\n" +
+				"title: サーバーの設定\n" +
+				"description: \"ユーザーの \\\"設定\\\"\"\n" +
+				"summary: 'コンピューター'\n" +
+				"slug: server\n" +
+				"tags: [サーバー, \"ユーザー\"]\n" +
+				"keywords:\n  - プリンター\n" +
+				"params:\n  title: ネスト\n" +
+				"---\n本文\n",
+			want: []string{"サーバーの設定", "コンピューター", "サーバー", "ユーザー", "プリンター"},
+		},
+		{
+			name: "toml",
+			input: "+++\n" +
+				"title = \"サーバーの設定\" # \"コメント\"\n" +
+				"summary = 'コンピューター'\n" +
+				"description = \"\\\"設定\\\"\"\n" +
+				"slug = \"server\"\n" +
+				"tags = [\"サーバー\", 'ユーザー']\n" +
+				"[params]\n" +
+				"title = \"ネスト\"\n" +
+				"+++\n本文\n",
+			want: []string{"サーバーの設定", "コンピューター", "サーバー", "ユーザー"},
+		},
+		{
+			// 複数行の文字列の中の key = 値 の形の行は項目ではない
+			name: "toml multi-line strings",
+			input: "+++\n" +
+				"body = \"\"\"\ntitle = \"cookie\"\n\\\"\"\"\ntags = [\"x\"]\n\"\"\"\n" +
+				"note = '''\n  summary = 'cookie'\n'''\n" +
+				"description = \"'''\" # '''\n" +
+				"summary = 'コンピューター'\n" +
+				"+++\n本文\n",
+			want: []string{"'''", "コンピューター"},
+		},
+		{
+			name: "json",
+			input: "{\n" +
+				"  \"title\": \"サーバーの設定\",\n" +
+				"  \"params\": {\"title\": \"ネスト\", \"list\": [1, {\"a\": []}]},\n" +
+				"  \"description\": \"\\\"設定\\\"\",\n" +
+				"  \"slug\": \"server\",\n" +
+				"  \"tags\": [\"サーバー\", {\"x\": \"ネスト\"}, \"ユーザー\"]\n" +
+				"}\n本文\n",
+			want: []string{"サーバーの設定", "サーバー", "ユーザー"},
+		},
+	}
+
+	fields := []string{"title", "description", "summary", "tags", "keywords"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, ok := findFrontMatter(tt.input)
+			if !ok {
+				t.Fatal("findFrontMatter() found no front matter")
+			}
+			var got []string
+			for _, s := range fm.fieldValueSpans(tt.input, fields) {
+				got = append(got, tt.input[s.Start:s.End])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fieldValueSpans() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplacer_ReplaceString_FrontMatter(t *testing.T) {
+	input := "---\n" +
+		"title: \"サーバーの設定\"\n" +
+		"slug: server\n" +
+		"aliases: [/サーバー/]\n" +
+		"tags:\n  - サーバー\n" +
+		"---\n\n" +
+		"# サーバー\n\nサーバーを設定する。\n"
+
+	tests := []struct {
+		name     string
+		markdown string
+		fields   []string
+		expected string
+	}{
+		{
+			name: "protected by default",
+			expected: "---\n" +
+				"title: \"サーバーの設定\"\n" +
+				"slug: server\n" +
+				"aliases: [/サーバー/]\n" +
+				"tags:\n  - サーバー\n" +
+				"---\n\n" +
+				"# サーバ\n\nサーバを設定する。\n",
+		},
+		{
+			name:   "configured fields",
+			fields: []string{"title", "tags"},
+			expected: "---\n" +
+				"title: \"サーバの設定\"\n" +
+				"slug: server\n" +
+				"aliases: [/サーバー/]\n" +
+				"tags:\n  - サーバ\n" +
+				"---\n\n" +
+				"# サーバ\n\nサーバを設定する。\n",
+		},
+		{
+			name:     "configured fields with goldmark",
+			markdown: MarkdownGoldmark,
+			fields:   []string{"title"},
+			expected: "---\n" +
+				"title: \"サーバの設定\"\n" +
+				"slug: server\n" +
+				"aliases: [/サーバー/]\n" +
+				"tags:\n  - サーバー\n" +
+				"---\n\n" +
+				"# サーバ\n\nサーバを設定する。\n",
+		},
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Markdown:          tt.markdown,
+				FrontMatterFields: tt.fields,
+				Rules:             []Rule{{Expected: "サーバ", Pattern: "サーバー"}},
+			}
+			if err := config.Rules[0].CompilePattern(); err != nil {
+				t.Fatalf("Failed to compile rule: %v", err)
+			}
+
+			result := NewReplacerWithLogger(config, logger).ReplaceString(input)
+			if result.Result != tt.expected {
+				t.Errorf("ReplaceString() = %q, want %q", result.Result, tt.expected)
+			}
+			for _, change := range result.Changes {
+				if got := input[change.Position : change.Position+change.Length]; got != change.From {
+					t.Errorf("change at %d:%d = %q, want %q", change.Line, change.Column, got, change.From)
+				}
+			}
+		})
+	}
+}
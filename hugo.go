@@ -210,7 +210,38 @@ func (hp *HugoProcessor) isPartOfPairedShortcode(text string, position int, shor
 	return false
 }
 
+// ProtectedSpans はショートコード、Markdownのコードとリンクなど、置換の対象から外す範囲を返す
+// 範囲は元のテキスト上の位置で、開始位置の順に並び、重なる範囲はまとめてある。
+// 先に検出した範囲の中身は後の検出から隠すため、例えばコードブロック内のコードスパンやショートコードは個別には検出しない。
+func (hp *HugoProcessor) ProtectedSpans(text string) []TextSpan {
+	var spans []TextSpan
+	masked := text
+
+	// Markdownコードブロックを最初に保護（優先度高）
+	for _, re := range []*regexp.Regexp{
+		hp.codeBlockRegex,
+		hp.codeSpanRegex,
+		hp.linkRegex,
+		hp.refLinkUseRegex,
+		hp.refLinkRegex,
+	} {
+		masked, spans = maskMatches(masked, spans, func(s string) [][]int {
+			return re.FindAllStringIndex(s, -1)
+		})
+	}
+
+	// Hugoショートコードを検出して保護
+	for _, sc := range hp.FindShortcodes(masked) {
+		spans = append(spans, TextSpan{Start: sc.Position, End: sc.Position + sc.Length})
+	}
+
+	return mergeSpans(spans)
+}
+
 // PreserveShortcodes はショートコードとMarkdownコードを一時的にプレースホルダーに置換する
+//
+// Deprecated: プレースホルダーの文字列がルールにマッチしたり、文書中の同じ文字列と衝突したりするため、
+// Replacer は ProtectedSpans で求めた範囲を置換の対象から外す方法を使う。
 func (hp *HugoProcessor) PreserveShortcodes(text string) (string, map[string]string) {
 	placeholders := make(map[string]string)
 	result := text
@@ -293,6 +324,8 @@ func (hp *HugoProcessor) PreserveShortcodes(text string) (string, map[string]str
 }
 
 // RestoreShortcodes はプレースホルダーを元のショートコードに戻す
+//
+// Deprecated: PreserveShortcodes を参照。
 func (hp *HugoProcessor) RestoreShortcodes(text string, placeholders map[string]string) string {
 	result := text
 	for placeholder, original := range placeholders {
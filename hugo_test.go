@@ -15,6 +15,7 @@
 package grh
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -160,6 +161,54 @@ Also {{< figure src="test.jpg" >}} here.`
 	}
 }
 
+func TestHugoProcessor_ProtectedSpans(t *testing.T) {
+	processor := NewHugoProcessor()
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "code span and shortcode",
+			input: "a `b` c {{< note >}}d{{< /note >}} e",
+			want:  []string{"`b`", "{{< note >}}d{{< /note >}}"},
+		},
+		{
+			name:  "code block hides inner code span and shortcode",
+			input: "a\n```\n`b` {{< note >}}\n```\nc",
+			want:  []string{"```\n`b` {{< note >}}\n```"},
+		},
+		{
+			name:  "link containing code span is merged",
+			input: "see [the `code`](https://example.com) here",
+			want:  []string{"[the `code`](https://example.com)"},
+		},
+		{
+			name:  "reference links",
+			input: "[text][ref]\n[ref]: https://example.com\n",
+			want:  []string{"[text][ref]", "[ref]: https://example.com"},
+		},
+		{
+			name:  "no protected regions",
+			input: "plain text",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, span := range processor.ProtectedSpans(tt.input) {
+				got = append(got, tt.input[span.Start:span.End])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ProtectedSpans() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestHugoProcessor_ValidateHugoMarkdown(t *testing.T) {
 	processor := NewHugoProcessor()
 
@@ -0,0 +1,208 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IgnoreRule はインポートしたルールのうち取り込まないものを選ぶ条件を表す構造体
+// 複数の条件を指定した場合は、すべてを満たすルールを取り込まない
+//
+// ルールファイルでは次のいずれかの形式で書ける
+//
+//	ignoreRules:
+//	  - rule-3f2a9c1e      # ルールのID
+//	  - /a/                # pattern: /a/ と等価
+//	  - pattern: /a/       # パース後のパターンが正規表現 a にマッチするルール
+//	  - expected: /b/      # expected が正規表現 b にマッチするルール
+//	  - expected: Cookie   # expected が Cookie と一致するルール
+//
+// pattern と expected は /.../ で囲んだ場合は正規表現として部分一致で、それ以外は完全一致で比較する。
+// pattern と比較するパース後のパターンは --rules-yaml、--rules-json の compiledPattern で確認できる
+type IgnoreRule struct {
+	ID       string `yaml:"id,omitempty" json:"id,omitempty"`
+	Pattern  string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Expected string `yaml:"expected,omitempty" json:"expected,omitempty"`
+}
+
+// UnmarshalYAML は文字列またはマッピングの形式の ignoreRules の要素を読み込む
+func (ir *IgnoreRule) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if isRegexpLiteral(value.Value) {
+			*ir = IgnoreRule{Pattern: value.Value}
+		} else {
+			*ir = IgnoreRule{ID: value.Value}
+		}
+		return nil
+	}
+
+	// UnmarshalYAML を再帰的に呼ばないよう別の型として読み込む
+	type plain IgnoreRule
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return err
+	}
+	*ir = IgnoreRule(p)
+	return nil
+}
+
+// MarshalYAML は読み込んだときと同じ形式になるよう、可能な場合は文字列として書き出す
+func (ir IgnoreRule) MarshalYAML() (interface{}, error) {
+	switch {
+	case ir.Pattern == "" && ir.Expected == "":
+		return ir.ID, nil
+	case ir.ID == "" && ir.Expected == "" && isRegexpLiteral(ir.Pattern):
+		return ir.Pattern, nil
+	}
+	type plain IgnoreRule
+	return plain(ir), nil
+}
+
+// String は条件を表示用の文字列にする
+func (ir IgnoreRule) String() string {
+	var parts []string
+	if ir.ID != "" {
+		parts = append(parts, "id: "+ir.ID)
+	}
+	if ir.Pattern != "" {
+		parts = append(parts, "pattern: "+ir.Pattern)
+	}
+	if ir.Expected != "" {
+		parts = append(parts, "expected: "+ir.Expected)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// ruleSelector は IgnoreRule をルールとの比較用にコンパイルしたもの
+type ruleSelector struct {
+	id       string
+	pattern  func(string) bool
+	expected func(string) bool
+}
+
+// compile は条件をコンパイルする
+func (ir IgnoreRule) compile() (*ruleSelector, error) {
+	if ir.ID == "" && ir.Pattern == "" && ir.Expected == "" {
+		return nil, fmt.Errorf("ignoreRules entry must specify id, pattern or expected")
+	}
+
+	pattern, err := compileTextMatcher(ir.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern in ignoreRules entry %s: %w", ir, err)
+	}
+	expected, err := compileTextMatcher(ir.Expected)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expected in ignoreRules entry %s: %w", ir, err)
+	}
+	return &ruleSelector{id: ir.ID, pattern: pattern, expected: expected}, nil
+}
+
+// matches はルールが条件をすべて満たすかを判定する
+func (s *ruleSelector) matches(rule *Rule) bool {
+	if s.id != "" && rule.id() != s.id {
+		return false
+	}
+	if s.pattern != nil && !s.pattern(rule.CompiledPattern()) {
+		return false
+	}
+	if s.expected != nil && !s.expected(rule.Expected) {
+		return false
+	}
+	return true
+}
+
+// compileTextMatcher は /.../ で囲まれた文字列は正規表現の部分一致、それ以外は完全一致で比較する関数を作る
+// 空文字列の場合は nil を返す
+func compileTextMatcher(s string) (func(string) bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !isRegexpLiteral(s) {
+		return func(text string) bool { return text == s }, nil
+	}
+	re, err := regexp.Compile(s[1 : len(s)-1])
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// isRegexpLiteral は文字列が /.../ の形式かを判定する
+func isRegexpLiteral(s string) bool {
+	return len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/")
+}
+
+// filterIgnoredRules は ignoreRules のいずれかの条件を満たすルールを除いた一覧と、
+// どのルールにも該当しなかった条件（IDの誤りや、パターンを変えて変わった導出IDなど）を返す
+func filterIgnoredRules(rules []Rule, ignores []IgnoreRule) ([]Rule, []IgnoreRule, error) {
+	selectors, err := compileSelectors(ignores)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filtered := []Rule{}
+	used := make([]bool, len(selectors))
+	for _, rule := range rules {
+		ignore := false
+		for i, selector := range selectors {
+			if selector.matches(&rule) {
+				used[i] = true
+				ignore = true
+			}
+		}
+		if !ignore {
+			filtered = append(filtered, rule)
+		}
+	}
+
+	var unmatched []IgnoreRule
+	for i, ignore := range ignores {
+		if !used[i] {
+			unmatched = append(unmatched, ignore)
+		}
+	}
+	return filtered, unmatched, nil
+}
+
+// ruleSelectors は複数の条件のいずれかを満たすルールを選ぶ
+type ruleSelectors []*ruleSelector
+
+// compileSelectors は条件の一覧をコンパイルする
+func compileSelectors(entries []IgnoreRule) (ruleSelectors, error) {
+	selectors := make(ruleSelectors, 0, len(entries))
+	for _, entry := range entries {
+		selector, err := entry.compile()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
+// matches はルールがいずれかの条件を満たすかを判定する
+func (selectors ruleSelectors) matches(rule *Rule) bool {
+	for _, selector := range selectors {
+		if selector.matches(rule) {
+			return true
+		}
+	}
+	return false
+}
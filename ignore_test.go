@@ -0,0 +1,157 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestIgnoreRule_UnmarshalYAML(t *testing.T) {
+	yamlContent := `
+- rule-3f2a9c1e
+- /a/
+- pattern: /b/
+- expected: Cookie
+- id: html
+  expected: HTML
+`
+	var got []IgnoreRule
+	if err := yaml.Unmarshal([]byte(yamlContent), &got); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+
+	want := []IgnoreRule{
+		{ID: "rule-3f2a9c1e"},
+		{Pattern: "/a/"},
+		{Pattern: "/b/"},
+		{Expected: "Cookie"},
+		{ID: "html", Expected: "HTML"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("len(IgnoreRules) = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("IgnoreRules[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// 書き出すと読み込んだときと同じ形式になる
+	data, err := yaml.Marshal(got[:2])
+	if err != nil {
+		t.Fatalf("yaml.Marshal() error = %v", err)
+	}
+	if string(data) != "- rule-3f2a9c1e\n- /a/\n" {
+		t.Errorf("yaml.Marshal() = %q", data)
+	}
+}
+
+func TestFilterIgnoredRules(t *testing.T) {
+	rules := []Rule{
+		{ID: "paren", Expected: "（$1）", Pattern: `/\(([^)]+)\)/`},
+		{Expected: "Cookie"},
+		{Expected: "jQuery", Pattern: "[jJ][qQ][uU][eE][rR][yY]"},
+		{Expected: "JavaScript"},
+	}
+	for i := range rules {
+		if err := rules[i].CompilePattern(); err != nil {
+			t.Fatalf("Failed to compile rule %d: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		ignores   []IgnoreRule
+		want      []string
+		unmatched []IgnoreRule
+		wantErr   bool
+	}{
+		{
+			name:    "by id",
+			ignores: []IgnoreRule{{ID: "paren"}},
+			want:    []string{"Cookie", "jQuery", "JavaScript"},
+		},
+		{
+			name:    "by parsed pattern with regexp",
+			ignores: []IgnoreRule{{Pattern: `/^\\\(/`}},
+			want:    []string{"Cookie", "jQuery", "JavaScript"},
+		},
+		{
+			name:    "by exact pattern",
+			ignores: []IgnoreRule{{Pattern: "[jJ][qQ][uU][eE][rR][yY]"}},
+			want:    []string{"（$1）", "Cookie", "JavaScript"},
+		},
+		{
+			name:    "by expected with regexp",
+			ignores: []IgnoreRule{{Expected: "/^J/"}},
+			want:    []string{"（$1）", "Cookie", "jQuery"},
+		},
+		{
+			name:      "by exact expected does not match partially",
+			ignores:   []IgnoreRule{{Expected: "Java"}},
+			want:      []string{"（$1）", "Cookie", "jQuery", "JavaScript"},
+			unmatched: []IgnoreRule{{Expected: "Java"}},
+		},
+		{
+			name:      "all conditions must match",
+			ignores:   []IgnoreRule{{ID: "paren", Expected: "Cookie"}},
+			want:      []string{"（$1）", "Cookie", "jQuery", "JavaScript"},
+			unmatched: []IgnoreRule{{ID: "paren", Expected: "Cookie"}},
+		},
+		{
+			// IDの誤りや、パターンを変えて変わった導出IDはどのルールにも該当しない
+			name:      "unmatched entries are reported",
+			ignores:   []IgnoreRule{{ID: "paren"}, {ID: "parens"}, {ID: "rule-deadbeef"}},
+			want:      []string{"Cookie", "jQuery", "JavaScript"},
+			unmatched: []IgnoreRule{{ID: "parens"}, {ID: "rule-deadbeef"}},
+		},
+		{
+			name:    "invalid regexp",
+			ignores: []IgnoreRule{{Expected: "/(/"}},
+			wantErr: true,
+		},
+		{
+			name:    "empty entry",
+			ignores: []IgnoreRule{{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, unmatched, err := filterIgnoredRules(rules, tt.ignores)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("filterIgnoredRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got []string
+			for _, rule := range filtered {
+				got = append(got, rule.Expected)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("filterIgnoredRules() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(unmatched, tt.unmatched) {
+				t.Errorf("filterIgnoredRules() unmatched = %v, want %v", unmatched, tt.unmatched)
+			}
+		})
+	}
+}
@@ -15,28 +15,45 @@
 package grh
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// LoadOptions はルールファイルの読み込み方法を表す構造体
+type LoadOptions struct {
+	// SkipSpecs はテストケースを検証せずに読み込む（grh test のように後でテストケースを実行する場合に使う）
+	SkipSpecs bool
+}
+
 // LoadConfig はYAMLファイルからConfigを読み込む
 func LoadConfig(path string) (*Config, error) {
+	return LoadConfigWithOptions(path, LoadOptions{})
+}
+
+// LoadConfigWithOptions は opts に従ってYAMLファイルからConfigを読み込む
+func LoadConfigWithOptions(path string, opts LoadOptions) (*Config, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file %q: %w", path, err)
 	}
 	defer file.Close()
 
-	return LoadConfigFromReader(file, path)
+	return loadConfigFromReader(file, path, opts)
 }
 
 // LoadConfigFromReader はio.ReaderからConfigを読み込む
 func LoadConfigFromReader(reader io.Reader, sourcePath string) (*Config, error) {
+	return loadConfigFromReader(reader, sourcePath, LoadOptions{})
+}
+
+func loadConfigFromReader(reader io.Reader, sourcePath string, opts LoadOptions) (*Config, error) {
 	content, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read content: %w", err)
@@ -47,23 +64,79 @@ func LoadConfigFromReader(reader io.Reader, sourcePath string) (*Config, error)
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
+	switch config.SpecFailures {
+	case "", SpecFailuresError, SpecFailuresWarn:
+	default:
+		return nil, fmt.Errorf("invalid specFailures %q in %s: must be %q or %q", config.SpecFailures, sourcePath, SpecFailuresError, SpecFailuresWarn)
+	}
+
+	if err := ValidateMarkdownBackend(config.Markdown); err != nil {
+		return nil, fmt.Errorf("invalid markdown in %s: %w", sourcePath, err)
+	}
+
 	// ソースパスを記録
 	config.SourcePaths = []string{sourcePath}
 
 	// ルールのパターンをコンパイル
+	ids := make(map[string]int)       // IDごとの最初のルールの番号
+	explicit := make(map[string]bool) // id: で指定したID
+	positions := make(map[string]int) // IDごとの rules 内の位置
+	rules := make([]Rule, 0, len(config.Rules))
 	for i := range config.Rules {
-		if err := config.Rules[i].CompilePattern(); err != nil {
-			return nil, fmt.Errorf("failed to compile pattern for rule %d: %w", i, err)
+		rule := &config.Rules[i]
+		hasID := rule.ID != ""
+		rule.setSourcePath(sourcePath)
+		rule.settings = config.replaceSettings()
+		if err := rule.CompilePattern(); err != nil {
+			return nil, rule.source.errorf("failed to compile pattern for rule %d: %w", i, err)
+		}
+
+		j, ok := ids[rule.ID]
+		switch {
+		case !ok:
+			ids[rule.ID] = i
+			explicit[rule.ID] = hasID
+			positions[rule.ID] = len(rules)
+			rules = append(rules, *rule)
+		case hasID || explicit[rule.ID]:
+			// 同じファイル内で id: に指定したIDが重複しているとマージや除外の対象が曖昧になる
+			return nil, rule.source.errorf("rule %d has the same id %q as rule %d (%s)", i, rule.ID, j, config.Rules[j].source)
+		default:
+			// id: を省略した同じ内容のルールは導出IDが同じになる。MergeConfigs と同じく後のルールで置き換えてまとめ、
+			// テストケースは両方のものを残す
+			merged := *rule
+			kept := rules[positions[rule.ID]]
+			merged.Specs = append(kept.Specs[:len(kept.Specs):len(kept.Specs)], rule.Specs...)
+			rules[positions[rule.ID]] = merged
 		}
 	}
+	config.Rules = rules
 
-	// ルールのテストケースを検証
-	for i, rule := range config.Rules {
-		if err := rule.ValidateSpecs(); err != nil {
-			return nil, fmt.Errorf("rule %d validation failed: %w", i, err)
+	// 文書全体のテストケースはこのファイルのルールで評価する（インポートがある場合は LoadConfigWithImports で置き換える）
+	for i := range config.Specs {
+		config.Specs[i].source.Path = sourcePath
+	}
+	if len(config.Specs) > 0 {
+		config.specSuites = []*specSuite{{specs: config.Specs, rules: config.Rules, settings: config.replaceSettings()}}
+	}
+
+	// ルールのテストケースを検証（最初の失敗で止めず、すべての失敗を報告する）
+	if !opts.SkipSpecs {
+		var errs []error
+		for _, rule := range config.Rules {
+			if err := rule.ValidateSpecs(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if config.SpecFailures == SpecFailuresWarn {
+			config.specWarnings = errs
+		} else if err := errors.Join(errs...); err != nil {
+			return nil, err
 		}
 	}
 
+	sortRulesByPriority(config.Rules)
+
 	return &config, nil
 }
 
@@ -100,6 +173,9 @@ func FindRuleFile(startDir string) (string, error) {
 }
 
 // MergeConfigs は複数のConfigをマージする（後のものが優先される）
+// ルールは configs の順、各Configの中ではその並び順に並べる。
+// 同じIDのルールが後のConfigにある場合は、先に現れた位置のまま後のルールで置き換える。
+// 最後に priority の大きい順に並べ替える（同じ priority の中では上記の順序を保つ）。
 func MergeConfigs(configs ...*Config) *Config {
 	if len(configs) == 0 {
 		return &Config{}
@@ -117,36 +193,149 @@ func MergeConfigs(configs ...*Config) *Config {
 	}
 	merged.SourcePaths = sourcePaths
 
-	// ルールをマージ（後のものが優先）
-	ruleMap := make(map[string]Rule)
+	// Markdownの解析方法と置換の対象にするフロントマターの項目は後のConfigで指定したものを使う
 	for _, config := range configs {
-		for _, rule := range config.Rules {
-			// ルールのキーとしてexpectedを使用（簡略化）
-			key := rule.Expected
-			ruleMap[key] = rule
+		if config.Markdown != "" {
+			merged.Markdown = config.Markdown
+		}
+		if len(config.FrontMatterFields) > 0 {
+			merged.FrontMatterFields = config.FrontMatterFields
 		}
 	}
 
-	// マップからスライスに変換
-	for _, rule := range ruleMap {
-		merged.Rules = append(merged.Rules, rule)
+	// 文書全体のテストケースを引き継ぐ
+	// 同じファイルを複数の経路からインポートしている場合に重複しないようにする
+	seenSuites := make(map[*specSuite]bool)
+	for _, config := range configs {
+		for _, suite := range config.specSuites {
+			if !seenSuites[suite] {
+				seenSuites[suite] = true
+				merged.specSuites = append(merged.specSuites, suite)
+			}
+		}
 	}
 
+	// specFailures: warn で失敗したテストケースと、どのルールにも該当しなかった ignoreRules の条件も引き継ぐ
+	// 同じファイルを複数の経路からインポートしている場合に重複しないようにする
+	seenWarnings := make(map[error]bool)
+	for _, config := range configs {
+		for _, warning := range config.specWarnings {
+			if !seenWarnings[warning] {
+				seenWarnings[warning] = true
+				merged.specWarnings = append(merged.specWarnings, warning)
+			}
+		}
+		for _, warning := range config.ignoreWarnings {
+			if !seenWarnings[warning] {
+				seenWarnings[warning] = true
+				merged.ignoreWarnings = append(merged.ignoreWarnings, warning)
+			}
+		}
+	}
+
+	// ルールをマージ（同じIDのルールは後のものが優先）
+	positions := make(map[string]int)
+	for _, config := range configs {
+		for _, rule := range config.Rules {
+			id := rule.id()
+			if i, ok := positions[id]; ok {
+				merged.Rules[i] = rule
+				continue
+			}
+			positions[id] = len(merged.Rules)
+			merged.Rules = append(merged.Rules, rule)
+		}
+	}
+
+	sortRulesByPriority(merged.Rules)
+
 	return merged
 }
 
+// sortRulesByPriority はルールを priority の大きい順に並べ替える（同じ priority の中では元の順序を保つ）
+func sortRulesByPriority(rules []Rule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+}
+
+// ErrImportCycle はルールファイルのインポートが循環していることを表す
+var ErrImportCycle = errors.New("import cycle detected")
+
+// ImportError はインポートしたルールファイルの読み込みで発生したエラーを表す
+// Chain は最初に読み込んだファイルからエラーが発生したファイルまでのインポートの連鎖
+type ImportError struct {
+	Chain []string
+	Err   error
+}
+
+func (e *ImportError) Error() string {
+	return strings.Join(e.Chain, " -> ") + ": " + e.Err.Error()
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
 // LoadConfigWithImports はインポートを含むConfigを読み込む
+// ルールは imports に書かれた順にインポートしたファイルのルール、最後にこのファイルのルールの順に並び、
+// 同じIDのルールはこのファイルのものが優先される
+//
+// 同じファイルを複数の経路からインポートしている場合でも、ファイルの読み込みは1度だけ行う。
+// インポートが循環している場合は ErrImportCycle を、インポートしたファイルでエラーが発生した場合は
+// インポートの連鎖を含む *ImportError を返す。
 func LoadConfigWithImports(path string) (*Config, error) {
-	config, err := LoadConfig(path)
+	return LoadConfigWithImportsOptions(path, LoadOptions{})
+}
+
+// LoadConfigWithImportsOptions は opts に従ってインポートを含むConfigを読み込む
+func LoadConfigWithImportsOptions(path string, opts LoadOptions) (*Config, error) {
+	loader := &importLoader{
+		opts:     opts,
+		files:    make(map[string]*Config),
+		resolved: make(map[string]*Config),
+	}
+	return loader.load(path, nil)
+}
+
+// importLoader はインポートを含むルールファイルを読み込む際の状態を表す
+type importLoader struct {
+	opts     LoadOptions
+	files    map[string]*Config // 読み込んだファイル（インポートは未解決）
+	resolved map[string]*Config // インポートを解決したファイル
+	stack    []string           // 読み込み中のファイル（循環の検出に使う）
+}
+
+// load は path のファイルとそのインポートを読み込む
+// chain は path をインポートしたファイルまでの連鎖（最初のファイルの場合は nil）
+func (l *importLoader) load(path string, chain []string) (*Config, error) {
+	chain = append(chain[:len(chain):len(chain)], path)
+	key := importKey(path)
+
+	if config, ok := l.resolved[key]; ok {
+		return config, nil
+	}
+	for _, loading := range l.stack {
+		if loading == key {
+			return nil, &ImportError{Chain: chain, Err: ErrImportCycle}
+		}
+	}
+
+	config, err := l.loadFile(path, chain)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(config.Imports) == 0 {
+		l.resolved[key] = config
 		return config, nil
 	}
 
-	configs := []*Config{config}
+	l.stack = append(l.stack, key)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	var configs []*Config
+	var ignoreWarnings []error
 	baseDir := filepath.Dir(path)
 
 	for _, imp := range config.Imports {
@@ -160,36 +349,91 @@ func LoadConfigWithImports(path string) (*Config, error) {
 		var importedConfig *Config
 		if imp.DisableImports {
 			// インポートの連鎖を無効にする場合は直接読み込み
-			importedConfig, err = LoadConfig(importPath)
+			importedConfig, err = l.loadFile(importPath, append(chain[:len(chain):len(chain)], importPath))
 		} else {
 			// 再帰的にインポートを処理
-			importedConfig, err = LoadConfigWithImports(importPath)
+			importedConfig, err = l.load(importPath, chain)
 		}
-
 		if err != nil {
-			return nil, fmt.Errorf("failed to load imported config %q: %w", importPath, err)
+			return nil, err
 		}
 
-		// ignoreRulesの処理（簡略化：expectedでマッチング）
+		// ignoreRulesの処理（ID、パターン、expectedでマッチング）
+		// 読み込んだConfigは他のファイルからのインポートと共有しているため、コピーしてから絞り込む
 		if len(imp.IgnoreRules) > 0 {
-			filteredRules := []Rule{}
-			for _, rule := range importedConfig.Rules {
-				ignore := false
-				for _, ignorePattern := range imp.IgnoreRules {
-					if strings.Contains(rule.Expected, ignorePattern) {
-						ignore = true
-						break
-					}
-				}
-				if !ignore {
-					filteredRules = append(filteredRules, rule)
-				}
+			filtered := *importedConfig
+			var unmatched []IgnoreRule
+			filtered.Rules, unmatched, err = filterIgnoredRules(importedConfig.Rules, imp.IgnoreRules)
+			if err != nil {
+				return nil, wrapImportError(chain, fmt.Errorf("failed to apply ignoreRules for %q: %w", importPath, err))
 			}
-			importedConfig.Rules = filteredRules
+			for _, ignore := range unmatched {
+				ignoreWarnings = append(ignoreWarnings, unmatchedIgnoreWarning(path, importPath, ignore, importedConfig.Rules))
+			}
+			importedConfig = &filtered
 		}
 
 		configs = append(configs, importedConfig)
 	}
+	// このファイルの文書全体のテストケースはインポートしたルールも含めて評価するため、後で付け直す
+	own := *config
+	own.specSuites = nil
+	configs = append(configs, &own)
+
+	merged := MergeConfigs(configs...)
+	merged.Version = config.Version
+	merged.ignoreWarnings = append(merged.ignoreWarnings, ignoreWarnings...)
+	if len(config.Specs) > 0 {
+		merged.specSuites = append(merged.specSuites, &specSuite{specs: config.Specs, rules: merged.Rules, settings: merged.replaceSettings()})
+	}
+	l.resolved[key] = merged
+	return merged, nil
+}
 
-	return MergeConfigs(configs...), nil
+// unmatchedIgnoreWarning はどのルールにも該当しなかった ignoreRules の条件の警告を作る
+// 文字列だけの要素は以前は expected との比較だったため、expected が一致するルールがある場合は書き換え方を示す
+func unmatchedIgnoreWarning(path, importPath string, ignore IgnoreRule, rules []Rule) error {
+	if ignore.Pattern == "" && ignore.Expected == "" {
+		for _, rule := range rules {
+			if rule.Expected == ignore.ID {
+				return fmt.Errorf("%s: ignoreRules entry %q matched no rule id in %q; plain strings are now rule ids, use \"expected: %s\" to ignore by expected", path, ignore.ID, importPath, ignore.ID)
+			}
+		}
+	}
+	return fmt.Errorf("%s: ignoreRules entry %s matched no rule in %q", path, ignore, importPath)
+}
+
+// loadFile は path のファイルを読み込む（インポートは解決しない）
+func (l *importLoader) loadFile(path string, chain []string) (*Config, error) {
+	key := importKey(path)
+	if config, ok := l.files[key]; ok {
+		return config, nil
+	}
+
+	config, err := LoadConfigWithOptions(path, l.opts)
+	if err != nil {
+		return nil, wrapImportError(chain, err)
+	}
+	l.files[key] = config
+	return config, nil
+}
+
+// wrapImportError はインポートしたファイルで発生したエラーにインポートの連鎖を付ける
+// 最初に読み込んだファイル自体のエラーはそのまま返す
+func wrapImportError(chain []string, err error) error {
+	if len(chain) <= 1 {
+		return err
+	}
+	return &ImportError{Chain: chain, Err: err}
+}
+
+// importKey は同じファイルを指すパスが同じ値になるよう、パスを絶対パスに変換する
+func importKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path
 }
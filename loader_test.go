@@ -15,6 +15,8 @@
 package grh
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -133,4 +135,594 @@ func TestMergeConfigs(t *testing.T) {
 	if len(merged.Rules) != 2 {
 		t.Errorf("len(Rules) = %d, want 2", len(merged.Rules))
 	}
+
+	// expected が同じでもIDが異なるルールは上書きしない
+	config3 := &Config{
+		Rules: []Rule{
+			{ID: "paren", Expected: "（$1）", Pattern: `\(([^)]+)\)`},
+			{ID: "bracket", Expected: "（$1）", Pattern: `（([^）]+)\)`},
+		},
+	}
+	config4 := &Config{
+		Rules: []Rule{
+			{ID: "paren", Expected: "（$1）", Pattern: `\(([^)\n]+)\)`}, // 同じIDで上書き
+		},
+	}
+	merged = MergeConfigs(config3, config4)
+	if len(merged.Rules) != 2 {
+		t.Fatalf("len(Rules) = %d, want 2", len(merged.Rules))
+	}
+	for _, rule := range merged.Rules {
+		if rule.ID == "paren" && rule.Pattern != `\(([^)\n]+)\)` {
+			t.Errorf("rule %q should be overridden by the later config, got pattern %q", rule.ID, rule.Pattern)
+		}
+	}
+}
+
+func TestMergeConfigs_Order(t *testing.T) {
+	config1 := &Config{
+		Rules: []Rule{
+			{ID: "a", Expected: "A"},
+			{ID: "b", Expected: "B"},
+			{ID: "c", Expected: "C"},
+		},
+	}
+	config2 := &Config{
+		Rules: []Rule{
+			{ID: "d", Expected: "D"},
+			{ID: "b", Expected: "B2"}, // 元の位置のまま上書き
+			{ID: "e", Expected: "E", Priority: 10},
+			{ID: "f", Expected: "F", Priority: -1},
+		},
+	}
+
+	// priority の大きい順、同じ priority の中ではConfigの順、Config内の順に並ぶ
+	want := []string{"E", "A", "B2", "C", "D", "F"}
+	for n := 0; n < 10; n++ {
+		merged := MergeConfigs(config1, config2)
+		var got []string
+		for _, rule := range merged.Rules {
+			got = append(got, rule.Expected)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("Rules = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLoadConfigWithImports_Order(t *testing.T) {
+	config, err := LoadConfigWithImports("testdata/yaml/with-imports.yml")
+	if err != nil {
+		t.Fatalf("LoadConfigWithImports() error = %v", err)
+	}
+
+	// インポートしたファイルのルール、このファイルのルールの順に並ぶ
+	want := []string{"HTML", "CSS", "React", "Vue.js"}
+	var got []string
+	for _, rule := range config.Rules {
+		got = append(got, rule.Expected)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Rules = %v, want %v", got, want)
+	}
+}
+
+func TestLoadConfigFromReader_DuplicateID(t *testing.T) {
+	yamlContent := `version: 1
+rules:
+  - id: paren
+    expected: （$1）
+    pattern: \(([^)]+)\)
+  - id: paren
+    expected: 「$1」
+    pattern: "\\[([^]]+)\\]"`
+
+	_, err := LoadConfigFromReader(strings.NewReader(yamlContent), "test.yml")
+	if err == nil || !strings.Contains(err.Error(), `"paren"`) {
+		t.Errorf("LoadConfigFromReader() error = %v, want duplicate id error", err)
+	}
+}
+
+func TestLoadConfigFromReader_DuplicateDerivedID(t *testing.T) {
+	// id: を省略した同じ内容のルールはエラーにせず、1つにまとめてテストケースは両方とも残す
+	yamlContent := `version: 1
+rules:
+  - expected: Cookie
+    specs:
+      - from: cookie
+        to: Cookie
+  - expected: jQuery
+  - expected: Cookie
+    specs:
+      - from: COOKIE
+        to: Cookie`
+
+	config, err := LoadConfigFromReader(strings.NewReader(yamlContent), "test.yml")
+	if err != nil {
+		t.Fatalf("LoadConfigFromReader() error = %v", err)
+	}
+	if len(config.Rules) != 2 || config.Rules[0].Expected != "Cookie" || config.Rules[1].Expected != "jQuery" {
+		t.Fatalf("Rules = %+v, want Cookie and jQuery", config.Rules)
+	}
+	if len(config.Rules[0].Specs) != 2 {
+		t.Errorf("Specs = %+v, want the specs of both rules", config.Rules[0].Specs)
+	}
+
+	// 導出IDと同じIDを id: に指定した場合はエラー
+	id := (&Rule{Expected: "Cookie"}).DerivedID()
+	yamlContent = "version: 1\nrules:\n  - expected: Cookie\n  - id: " + id + "\n    expected: Cookies\n"
+	if _, err := LoadConfigFromReader(strings.NewReader(yamlContent), "test.yml"); err == nil || !strings.Contains(err.Error(), id) {
+		t.Errorf("LoadConfigFromReader() error = %v, want duplicate id error", err)
+	}
+}
+
+func TestLoadConfigWithImports_IgnoreRules(t *testing.T) {
+	dir := t.TempDir()
+	base := `version: 1
+rules:
+  - id: html
+    expected: HTML
+  - expected: CSS
+`
+	css := Rule{Expected: "CSS"}
+	main := `version: 1
+imports:
+  - path: base.yml
+    ignoreRules:
+      - html
+      - ` + css.DerivedID() + `
+rules:
+  - expected: React
+`
+	if err := os.WriteFile(filepath.Join(dir, "base.yml"), []byte(base), 0644); err != nil {
+		t.Fatalf("Failed to write base.yml: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.yml"), []byte(main), 0644); err != nil {
+		t.Fatalf("Failed to write main.yml: %v", err)
+	}
+
+	config, err := LoadConfigWithImports(filepath.Join(dir, "main.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfigWithImports() error = %v", err)
+	}
+
+	// 明示したIDと導出したIDのどちらでも除外できる
+	if len(config.Rules) != 1 || config.Rules[0].Expected != "React" {
+		t.Errorf("Rules = %+v, want only React", config.Rules)
+	}
+	if warnings := config.IgnoreWarnings(); len(warnings) != 0 {
+		t.Errorf("IgnoreWarnings() = %v, want none", warnings)
+	}
+}
+
+func TestLoadConfigWithImports_UnmatchedIgnoreRules(t *testing.T) {
+	dir := writeRuleFiles(t, map[string]string{
+		"main.yml":  "version: 1\nimports:\n  - path: left.yml\n  - path: right.yml\nrules:\n  - expected: Main\n",
+		"left.yml":  "version: 1\nimports:\n  - path: base.yml\n    ignoreRules:\n      - htlm\n      - html\nrules: []\n",
+		"right.yml": "version: 1\nimports:\n  - path: left.yml\nrules: []\n",
+		"base.yml":  "version: 1\nrules:\n  - id: html\n    expected: HTML\n",
+	})
+
+	config, err := LoadConfigWithImports(filepath.Join(dir, "main.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfigWithImports() error = %v", err)
+	}
+
+	// どのルールにも該当しなかった条件は、複数の経路からインポートしていても1度だけ警告する
+	warnings := config.IgnoreWarnings()
+	if len(warnings) != 1 {
+		t.Fatalf("IgnoreWarnings() = %v, want 1 warning", warnings)
+	}
+	if got := warnings[0].Error(); !strings.Contains(got, "left.yml: ignoreRules entry {id: htlm} matched no rule") {
+		t.Errorf("IgnoreWarnings()[0] = %q", got)
+	}
+}
+
+func TestLoadConfigWithImports_PlainStringIgnoreRules(t *testing.T) {
+	// 以前は文字列だけの要素を expected と比較していたため、expected が一致するルールがあれば書き換え方を示す
+	dir := writeRuleFiles(t, map[string]string{
+		"main.yml": "version: 1\nimports:\n  - path: base.yml\n    ignoreRules:\n      - Cookie\nrules: []\n",
+		"base.yml": "version: 1\nrules:\n  - expected: Cookie\n",
+	})
+
+	config, err := LoadConfigWithImports(filepath.Join(dir, "main.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfigWithImports() error = %v", err)
+	}
+	if len(config.Rules) != 1 {
+		t.Errorf("Rules = %+v, want Cookie to be kept", config.Rules)
+	}
+	warnings := config.IgnoreWarnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), `use "expected: Cookie"`) {
+		t.Errorf("IgnoreWarnings() = %v, want a migration hint", warnings)
+	}
+}
+
+// writeRuleFiles はテスト用のルールファイルをディレクトリに書き出す
+func writeRuleFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigWithImports_Cycle(t *testing.T) {
+	dir := writeRuleFiles(t, map[string]string{
+		"a.yml": "version: 1\nimports:\n  - path: b.yml\nrules:\n  - expected: A\n",
+		"b.yml": "version: 1\nimports:\n  - path: ./a.yml\nrules:\n  - expected: B\n",
+	})
+
+	_, err := LoadConfigWithImports(filepath.Join(dir, "a.yml"))
+	if !errors.Is(err, ErrImportCycle) {
+		t.Fatalf("LoadConfigWithImports() error = %v, want ErrImportCycle", err)
+	}
+
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || len(importErr.Chain) != 3 {
+		t.Fatalf("error should be *ImportError with the chain a -> b -> a, got %v", err)
+	}
+	if filepath.Base(importErr.Chain[0]) != "a.yml" || filepath.Base(importErr.Chain[2]) != "a.yml" {
+		t.Errorf("Chain = %v", importErr.Chain)
+	}
+}
+
+func TestLoadConfigWithImports_Diamond(t *testing.T) {
+	dir := writeRuleFiles(t, map[string]string{
+		"main.yml":  "version: 1\nimports:\n  - path: left.yml\n  - path: right.yml\nrules:\n  - expected: Main\n",
+		"left.yml":  "version: 1\nimports:\n  - path: base.yml\nrules:\n  - expected: Left\n",
+		"right.yml": "version: 1\nimports:\n  - path: base.yml\n    ignoreRules:\n      - expected: Ignored\nrules:\n  - expected: Right\n",
+		"base.yml":  "version: 1\nrules:\n  - expected: Base\n  - expected: Ignored\n",
+	})
+
+	config, err := LoadConfigWithImports(filepath.Join(dir, "main.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfigWithImports() error = %v", err)
+	}
+
+	// 2つの経路からインポートした base.yml のルールは1つにまとまり、
+	// 片方の経路での ignoreRules は他方の経路に影響しない
+	want := []string{"Base", "Ignored", "Left", "Right", "Main"}
+	var got []string
+	for _, rule := range config.Rules {
+		got = append(got, rule.Expected)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Rules = %v, want %v", got, want)
+	}
+}
+
+func TestLoadConfigWithImports_ErrorChain(t *testing.T) {
+	dir := writeRuleFiles(t, map[string]string{
+		"grh.yml":  "version: 1\nimports:\n  - path: team.yml\nrules: []\n",
+		"team.yml": "version: 1\nimports:\n  - path: base.yml\nrules: []\n",
+		"base.yml": "version: 1\nrules:\n  - expected: [\n",
+	})
+
+	_, err := LoadConfigWithImports(filepath.Join(dir, "grh.yml"))
+	if err == nil {
+		t.Fatal("LoadConfigWithImports() should fail for invalid YAML in an imported file")
+	}
+
+	want := filepath.Join(dir, "grh.yml") + " -> " + filepath.Join(dir, "team.yml") + " -> " + filepath.Join(dir, "base.yml") + ": "
+	if !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), "line") {
+		t.Errorf("error = %q, want prefix %q and the line number", err.Error(), want)
+	}
+}
+
+func TestLoadConfigFromReader_SourcePositions(t *testing.T) {
+	yamlContent := `version: 1
+rules:
+  - expected: Cookie
+    specs:
+      - from: cookie
+        to: Cookie
+  - expected: jQuery
+    pattern: "[jJ][qQ][uU][eE][rR][yY]"
+`
+	config, err := LoadConfigFromReader(strings.NewReader(yamlContent), "test.yml")
+	if err != nil {
+		t.Fatalf("LoadConfigFromReader() error = %v", err)
+	}
+
+	if got := config.Rules[0].Source(); got != (SourcePosition{Path: "test.yml", Line: 3, Column: 5}) {
+		t.Errorf("Rules[0].Source() = %+v", got)
+	}
+	if got := config.Rules[0].Specs[0].Source(); got != (SourcePosition{Path: "test.yml", Line: 5, Column: 9}) {
+		t.Errorf("Rules[0].Specs[0].Source() = %+v", got)
+	}
+	if got := config.Rules[1].Source().String(); got != "test.yml:7:5" {
+		t.Errorf("Rules[1].Source() = %q, want %q", got, "test.yml:7:5")
+	}
+}
+
+func TestLoadConfigFromReader_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		name        string
+		yamlContent string
+		want        string
+	}{
+		{
+			name: "invalid pattern",
+			yamlContent: `version: 1
+rules:
+  - expected: Cookie
+  - expected: broken
+    pattern: "("
+`,
+			want: "test.yml:4:5: failed to compile pattern for rule 1",
+		},
+		{
+			name: "spec failure",
+			yamlContent: `version: 1
+rules:
+  - expected: JavaScript
+    specs:
+      - from: javascript
+        to: JavaScript
+      - from: JAVASCRIPT
+        to: JavaScprit
+`,
+			want: "test.yml:7:9: spec failed",
+		},
+		{
+			name: "duplicate id",
+			yamlContent: `version: 1
+rules:
+  - id: cookie
+    expected: Cookie
+  - id: cookie
+    expected: COOKIE
+`,
+			want: "test.yml:5:5: rule 1 has the same id \"cookie\" as rule 0 (test.yml:3:5)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfigFromReader(strings.NewReader(tt.yamlContent), "test.yml")
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("LoadConfigFromReader() error = %v, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// specFailuresTestYAML は2つのルールでテストケースが失敗するルールファイル
+const specFailuresTestYAML = `version: 1
+%s
+rules:
+  - expected: JavaScript
+    specs:
+      - from: javascript
+        to: JavaScprit
+      - from: JAVASCRIPT
+        to: JavaScprit
+  - expected: Cookie
+    specs:
+      - from: cookie
+        to: Cookie
+  - expected: jQuery
+    pattern: "[jJ][qQ][uU][eE][rR][yY]"
+    specs:
+      - from: jquery
+        to: JQuery
+`
+
+func TestLoadConfigFromReader_AllSpecFailures(t *testing.T) {
+	_, err := LoadConfigFromReader(strings.NewReader(fmt.Sprintf(specFailuresTestYAML, "")), "test.yml")
+	if err == nil {
+		t.Fatal("LoadConfigFromReader() should fail")
+	}
+
+	// 最初の失敗で止めず、すべての失敗を報告する
+	for _, want := range []string{"test.yml:6:9: spec failed", "test.yml:8:9: spec failed", "test.yml:17:9: spec failed"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Error should contain %q, got:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "test.yml:12:9") {
+		t.Errorf("Error should not contain the passing spec, got:\n%v", err)
+	}
+}
+
+func TestLoadConfigFromReader_SpecFailuresWarn(t *testing.T) {
+	config, err := LoadConfigFromReader(strings.NewReader(fmt.Sprintf(specFailuresTestYAML, "specFailures: warn")), "test.yml")
+	if err != nil {
+		t.Fatalf("LoadConfigFromReader() error = %v", err)
+	}
+	if len(config.Rules) != 3 {
+		t.Errorf("Expected 3 rules, got %d", len(config.Rules))
+	}
+
+	// 失敗したルールごとに警告を返す
+	warnings := config.SpecWarnings()
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %d: %v", len(warnings), warnings)
+	}
+	if !strings.HasPrefix(warnings[1].Error(), "test.yml:17:9: spec failed") {
+		t.Errorf("Unexpected warning: %v", warnings[1])
+	}
+
+	// インポートしたファイルの警告も引き継ぐ
+	dir := writeRuleFiles(t, map[string]string{
+		"shared.yml": fmt.Sprintf(specFailuresTestYAML, "specFailures: warn"),
+		"grh.yml":    "version: 1\nimports:\n  - path: shared.yml\nrules: []\n",
+	})
+	config, err = LoadConfigWithImports(filepath.Join(dir, "grh.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfigWithImports() error = %v", err)
+	}
+	if got := len(config.SpecWarnings()); got != 2 {
+		t.Errorf("Expected 2 warnings from imported file, got %d", got)
+	}
+
+	_, err = LoadConfigFromReader(strings.NewReader(fmt.Sprintf(specFailuresTestYAML, "specFailures: ignore")), "test.yml")
+	if err == nil || !strings.Contains(err.Error(), "invalid specFailures") {
+		t.Errorf("Expected invalid specFailures error, got %v", err)
+	}
+}
+
+func TestLoadConfigWithImportsOptions_SkipSpecs(t *testing.T) {
+	dir := writeRuleFiles(t, map[string]string{
+		"shared.yml": fmt.Sprintf(specFailuresTestYAML, ""),
+		"grh.yml":    "version: 1\nimports:\n  - path: shared.yml\nrules: []\n",
+	})
+
+	if _, err := LoadConfigWithImports(filepath.Join(dir, "grh.yml")); err == nil {
+		t.Error("LoadConfigWithImports() should fail on spec failures")
+	}
+
+	config, err := LoadConfigWithImportsOptions(filepath.Join(dir, "grh.yml"), LoadOptions{SkipSpecs: true})
+	if err != nil {
+		t.Fatalf("LoadConfigWithImportsOptions() error = %v", err)
+	}
+	if len(config.Rules) != 3 {
+		t.Fatalf("Expected 3 rules, got %d", len(config.Rules))
+	}
+
+	results, err := config.Rules[0].RunSpecs()
+	if err != nil {
+		t.Fatalf("RunSpecs() error = %v", err)
+	}
+	if len(results) != 2 || results[0].Passed || results[0].Got != "JavaScript" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
+
+func TestLoadConfigWithImports_DocumentSpecs(t *testing.T) {
+	dir := writeRuleFiles(t, map[string]string{
+		"shared.yml": `version: 1
+rules:
+  - expected: Cookie
+  - expected: jQuery
+    pattern: "[jJ][qQ][uU][eE][rR][yY]"
+specs:
+  - from: "cookie と jquery"
+    to: "Cookie と jQuery"
+`,
+		"grh.yml": `version: 1
+imports:
+  - path: shared.yml
+    ignoreRules:
+      - expected: jQuery
+rules:
+  - expected: クッキー
+    pattern: Cookie
+specs:
+  - from: |
+      cookie と jquery
+      ` + "`cookie`" + `
+    to: |
+      クッキー と jquery
+      ` + "`cookie`" + `
+  - keep: jquery
+`,
+	})
+
+	config, err := LoadConfigWithImports(filepath.Join(dir, "grh.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfigWithImports() error = %v", err)
+	}
+
+	// インポートしたファイルのテストケースは、そのファイルで読み込んだルールで評価する
+	results := config.RunDocumentSpecs()
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if !result.Passed {
+			t.Errorf("Spec at %s failed: %s", result.Spec.Source(), result.Description())
+		}
+	}
+	if got := results[0].Spec.Source(); got.Path != filepath.Join(dir, "shared.yml") || got.Line != 7 {
+		t.Errorf("results[0].Spec.Source() = %v", got)
+	}
+	if got := results[1].Spec.Source(); got.Path != filepath.Join(dir, "grh.yml") || got.Line != 10 {
+		t.Errorf("results[1].Spec.Source() = %v", got)
+	}
+}
+
+func TestConfig_RunDocumentSpecs(t *testing.T) {
+	config := &Config{
+		Rules: []Rule{
+			{Expected: "Cookie"},
+			{Expected: "クッキー", Pattern: "Cookie"},
+		},
+		Specs: []Spec{
+			{From: "cookie\njquery\n", To: "Cookie\njquery\n"},
+		},
+	}
+	for i := range config.Rules {
+		if err := config.Rules[i].CompilePattern(); err != nil {
+			t.Fatalf("Failed to compile rule %d: %v", i, err)
+		}
+	}
+
+	// ルールの組み合わせで期待と異なる結果になった場合は差分を返す
+	results := config.RunDocumentSpecs()
+	if len(results) != 1 || results[0].Passed {
+		t.Fatalf("Expected 1 failed result, got %+v", results)
+	}
+	if got := results[0].Description(); !strings.Contains(got, "-Cookie\n+クッキー\n") {
+		t.Errorf("Description() should contain the diff, got:\n%s", got)
+	}
+}
+
+func TestLoadConfigFromReader_Markdown(t *testing.T) {
+	config, err := LoadConfigFromReader(strings.NewReader("version: 1\nmarkdown: goldmark\nrules: []\n"), "test.yml")
+	if err != nil {
+		t.Fatalf("LoadConfigFromReader() error = %v", err)
+	}
+	if config.Markdown != MarkdownGoldmark {
+		t.Errorf("Markdown = %q, want %q", config.Markdown, MarkdownGoldmark)
+	}
+
+	// 後のConfigで指定したものを使う
+	merged := MergeConfigs(config, &Config{}, &Config{Markdown: MarkdownRegexp})
+	if merged.Markdown != MarkdownRegexp {
+		t.Errorf("Merged Markdown = %q, want %q", merged.Markdown, MarkdownRegexp)
+	}
+	merged = MergeConfigs(config, &Config{})
+	if merged.Markdown != MarkdownGoldmark {
+		t.Errorf("Merged Markdown = %q, want %q", merged.Markdown, MarkdownGoldmark)
+	}
+
+	if _, err := LoadConfigFromReader(strings.NewReader("version: 1\nmarkdown: blackfriday\nrules: []\n"), "test.yml"); err == nil {
+		t.Error("LoadConfigFromReader() should fail for unknown markdown backend")
+	}
+}
+
+func TestLoadConfigFromReader_MarkdownSpecs(t *testing.T) {
+	// document: true のテストケースと文書全体のテストケースは、ルールファイルの markdown の設定で評価する
+	// regexp ではチルダのフェンスやHTMLコメントを保護しないため、goldmark でなければ失敗する
+	rules := `version: 1
+markdown: %s
+rules:
+  - expected: Cookie
+    specs:
+      - keep: "~~~\ncookie\n~~~\n"
+        document: true
+specs:
+  - from: "<!-- cookie -->\ncookie\n"
+    to: "<!-- cookie -->\nCookie\n"
+`
+
+	config, err := LoadConfigFromReader(strings.NewReader(fmt.Sprintf(rules, MarkdownGoldmark)), "test.yml")
+	if err != nil {
+		t.Fatalf("LoadConfigFromReader() error = %v", err)
+	}
+	for _, result := range config.RunDocumentSpecs() {
+		if !result.Passed {
+			t.Errorf("RunDocumentSpecs() failed: %s", result.Description())
+		}
+	}
+
+	if _, err := LoadConfigFromReader(strings.NewReader(fmt.Sprintf(rules, MarkdownRegexp)), "test.yml"); err == nil {
+		t.Error("LoadConfigFromReader() should fail with the regexp backend")
+	}
 }
@@ -0,0 +1,187 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// 置換の対象から外す範囲を求めるMarkdownの解析方法（Config の markdown で指定する）
+const (
+	MarkdownRegexp   = "regexp"   // コードやリンク、ショートコードを正規表現で検出する（デフォルト）
+	MarkdownGoldmark = "goldmark" // goldmark でCommonMark（GFM拡張を含む）として解析し、文章の部分だけを置換の対象にする
+)
+
+// ValidateMarkdownBackend は Config の markdown や --markdown の値が対応している解析方法かを検証する
+func ValidateMarkdownBackend(backend string) error {
+	switch backend {
+	case "", MarkdownRegexp, MarkdownGoldmark:
+		return nil
+	default:
+		return fmt.Errorf("unknown markdown backend %q: must be %q or %q", backend, MarkdownRegexp, MarkdownGoldmark)
+	}
+}
+
+// protection は文書の中で置換の対象から外す範囲の検出結果
+type protection struct {
+	spans  []TextSpan            // 置換の対象から外す範囲
+	code   []TextSpan            // そのうち grh-disable などのコメントを探さない範囲（コードなど）
+	scopes map[string][]TextSpan // Markdownの要素ごとの文章の範囲（ルールの in、notIn に使う。解析していない場合は nil）
+}
+
+// protector は文書から置換の対象から外す範囲を求める
+type protector interface {
+	protect(text string) protection
+}
+
+// newProtector は markdown の値に対応する protector を作る
+func newProtector(backend string) protector {
+	if backend == MarkdownGoldmark {
+		return NewGoldmarkProcessor()
+	}
+	return NewHugoProcessor()
+}
+
+// protect は ProtectedSpans の範囲を保護する（コメントも範囲の外だけを探す）
+func (hp *HugoProcessor) protect(text string) protection {
+	spans := hp.ProtectedSpans(text)
+	return protection{spans: spans, code: spans}
+}
+
+// GoldmarkProcessor は goldmark でMarkdownを解析し、置換してよい文章の範囲を求める
+// チルダのフェンスやインデントによるコードブロック、自動リンク、インラインHTML、HTMLコメント、
+// リンクテキスト内の入れ子の括弧、複数のバッククォートによるコードスパンも正しく扱える
+type GoldmarkProcessor struct {
+	markdown goldmark.Markdown
+	hugo     *HugoProcessor
+}
+
+// NewGoldmarkProcessor は新しいGoldmarkProcessorを作成する
+func NewGoldmarkProcessor() *GoldmarkProcessor {
+	return &GoldmarkProcessor{
+		markdown: goldmark.New(goldmark.WithExtensions(extension.GFM)),
+		hugo:     NewHugoProcessor(),
+	}
+}
+
+// TextSpans は文書のうち文章（見出しや段落、リンクテキストなどのテキスト）の範囲を返す
+// コード、URL、HTML、Markdownの記号は含まない。範囲は開始位置の順に並び、隣接する範囲はまとめてある。
+func (gp *GoldmarkProcessor) TextSpans(source string) []TextSpan {
+	return gp.scan(source).text
+}
+
+// ScopeSpans はMarkdownの要素（ScopeHeading など）ごとに、その要素の中にある文章の範囲を返す
+// 要素が入れ子になっている場合（リストの項目の中のリンクテキストなど）は、それぞれの要素の範囲に含まれる
+func (gp *GoldmarkProcessor) ScopeSpans(source string) map[string][]TextSpan {
+	return gp.scan(source).scopes
+}
+
+// ProtectedSpans は TextSpans 以外の範囲と、Hugoショートコードの範囲を返す
+// goldmark はHugoショートコードを解釈しないため、ショートコードは HugoProcessor と同じ方法で検出する
+func (gp *GoldmarkProcessor) ProtectedSpans(source string) []TextSpan {
+	return gp.protect(source).spans
+}
+
+func (gp *GoldmarkProcessor) protect(source string) protection {
+	result := gp.scan(source)
+	code := result.code
+	for _, sc := range gp.hugo.FindShortcodes(maskSpans(source, code)) {
+		code = append(code, TextSpan{Start: sc.Position, End: sc.Position + sc.Length})
+	}
+	code = mergeSpans(code)
+
+	spans := append(complementSpans(result.text, len(source)), code...)
+	return protection{spans: mergeSpans(spans), code: code, scopes: result.scopes}
+}
+
+// scanResult は goldmark で文書を解析した結果
+type scanResult struct {
+	text   []TextSpan            // 文章の範囲
+	code   []TextSpan            // コードの範囲
+	scopes map[string][]TextSpan // Markdownの要素ごとの文章の範囲
+}
+
+// scan は文書を解析し、文章の範囲とコードの範囲、Markdownの要素ごとの文章の範囲を返す
+func (gp *GoldmarkProcessor) scan(source string) scanResult {
+	var textSpans, code []TextSpan
+	scopes := make(map[string][]TextSpan)
+	src := []byte(source)
+	doc := gp.markdown.Parser().Parse(text.NewReader(src))
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.CodeSpan:
+			// コードスパンの中身は子の Text ノードとして表される
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					code = append(code, TextSpan{Start: t.Segment.Start, End: t.Segment.Stop})
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeBlock, *ast.FencedCodeBlock:
+			if lines := n.Lines(); lines.Len() > 0 {
+				code = append(code, TextSpan{Start: lines.At(0).Start, End: lines.At(lines.Len() - 1).Stop})
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.AutoLink, *ast.RawHTML, *ast.HTMLBlock:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			span := TextSpan{Start: n.Segment.Start, End: n.Segment.Stop}
+			textSpans = append(textSpans, span)
+			for _, scope := range nodeScopes(n) {
+				scopes[scope] = append(scopes[scope], span)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for scope, spans := range scopes {
+		scopes[scope] = mergeSpans(spans)
+	}
+	return scanResult{text: mergeSpans(textSpans), code: code, scopes: scopes}
+}
+
+// nodeScopes はノードを含むMarkdownの要素の一覧を返す
+func nodeScopes(n ast.Node) []string {
+	var scopes []string
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		switch p.(type) {
+		case *ast.Heading:
+			scopes = append(scopes, ScopeHeading)
+		case *ast.Paragraph:
+			scopes = append(scopes, ScopeParagraph)
+		case *ast.Link:
+			scopes = append(scopes, ScopeLinkText)
+		case *ast.Image:
+			scopes = append(scopes, ScopeImageAlt)
+		case *east.TableCell:
+			scopes = append(scopes, ScopeTableCell)
+		case *ast.ListItem:
+			scopes = append(scopes, ScopeListItem)
+		case *ast.Blockquote:
+			scopes = append(scopes, ScopeBlockquote)
+		}
+	}
+	return scopes
+}
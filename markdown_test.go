@@ -0,0 +1,116 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestGoldmarkProcessor_TextSpans(t *testing.T) {
+	processor := NewGoldmarkProcessor()
+
+	input := "# 見出し\n\n" +
+		"段落と `code` と ``a ` b`` と [link [x] text](https://example.com) と ![alt](image.png)\n" +
+		"<span>inline</span> <!-- comment --> https://example.com\n\n" +
+		"~~~\nfenced\n~~~\n\n" +
+		"    indented\n\n" +
+		"<div>\nblock\n</div>\n"
+
+	var got []string
+	for _, span := range processor.TextSpans(input) {
+		got = append(got, input[span.Start:span.End])
+	}
+	want := []string{"見出し", "段落と ", " と ", " と ", "link [x] text", " と ", "alt", "inline", " ", " "}
+	if len(got) != len(want) {
+		t.Fatalf("TextSpans() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("TextSpans()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReplacer_ReplaceString_Goldmark(t *testing.T) {
+	config := &Config{
+		Markdown: MarkdownGoldmark,
+		Rules: []Rule{
+			{Expected: "Cookie", Pattern: "[Cc]ookie"},
+		},
+	}
+	for i := range config.Rules {
+		if err := config.Rules[i].CompilePattern(); err != nil {
+			t.Fatalf("Failed to compile rule %d: %v", i, err)
+		}
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	replacer := NewReplacerWithLogger(config, logger)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "tilde fence",
+			input:    "cookie\n\n~~~\ncookie\n~~~\n",
+			expected: "Cookie\n\n~~~\ncookie\n~~~\n",
+		},
+		{
+			name:     "indented code block",
+			input:    "cookie\n\n    cookie\n",
+			expected: "Cookie\n\n    cookie\n",
+		},
+		{
+			name:     "multi-backtick code span",
+			input:    "cookie ``a ` cookie``\n",
+			expected: "Cookie ``a ` cookie``\n",
+		},
+		{
+			name:     "autolinks and inline HTML",
+			input:    "cookie <https://cookie.example.com> https://example.com/cookie <a title=\"cookie\">cookie</a>\n",
+			expected: "Cookie <https://cookie.example.com> https://example.com/cookie <a title=\"cookie\">Cookie</a>\n",
+		},
+		{
+			name:     "link text is prose but URL is not",
+			input:    "[cookie [1]](https://example.com/cookie)\n",
+			expected: "[Cookie [1]](https://example.com/cookie)\n",
+		},
+		{
+			name:     "HTML comment and suppression",
+			input:    "<!-- cookie -->\n\n<!-- grh-disable-next-line -->\ncookie\ncookie\n",
+			expected: "<!-- cookie -->\n\n<!-- grh-disable-next-line -->\ncookie\nCookie\n",
+		},
+		{
+			name:     "Hugo shortcode",
+			input:    "cookie {{< note >}}cookie{{< /note >}}\n",
+			expected: "Cookie {{< note >}}cookie{{< /note >}}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := replacer.ReplaceString(tt.input)
+			if result.Result != tt.expected {
+				t.Errorf("ReplaceString() = %q, want %q", result.Result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// textEdit は1箇所の書き換えを表す（start、oldLenは書き換え前のテキスト上のバイト数）
+type textEdit struct {
+	start  int
+	oldLen int
+	newLen int
+	delta  int // この書き換えより前の書き換えによる位置のずれ（バイト数）
+}
+
+// editMap は1回の変換で行われた書き換えの一覧（startの昇順）
+// 変換前と変換後のテキスト上の位置を相互に移すために使う。位置は二分探索で求める
+type editMap []textEdit
+
+// toSource は変換後のテキスト上の位置を変換前のテキスト上の位置に戻す
+// 書き換えられた範囲の内側を指す位置は、開始位置なら範囲の先頭に、終了位置なら範囲の末尾に寄せる
+func (m editMap) toSource(pos int, isEnd bool) int {
+	i := sort.Search(len(m), func(i int) bool {
+		outEnd := m[i].start + m[i].delta + m[i].newLen
+		if isEnd {
+			return pos <= outEnd
+		}
+		return pos < outEnd
+	})
+	if i == len(m) {
+		return pos - m.totalDelta()
+	}
+	e := m[i]
+	outStart := e.start + e.delta
+	switch {
+	case pos < outStart || isEnd && pos == outStart:
+		return pos - e.delta
+	case isEnd:
+		return e.start + e.oldLen
+	default:
+		return e.start
+	}
+}
+
+// toTarget は変換前のテキスト上の位置を変換後のテキスト上の位置に移す
+// 書き換えられた範囲の内側を指す位置は、開始位置なら書き換え後のテキストの先頭に、終了位置なら末尾に寄せる
+func (m editMap) toTarget(pos int, isEnd bool) int {
+	i := sort.Search(len(m), func(i int) bool {
+		return m[i].start+m[i].oldLen > pos
+	})
+	if i == len(m) {
+		return pos + m.totalDelta()
+	}
+	e := m[i]
+	if e.start < pos {
+		if isEnd {
+			return e.start + e.delta + e.newLen
+		}
+		return e.start + e.delta
+	}
+	return pos + e.delta
+}
+
+// totalDelta はすべての書き換えによる位置のずれを返す
+func (m editMap) totalDelta() int {
+	if len(m) == 0 {
+		return 0
+	}
+	last := m[len(m)-1]
+	return last.delta + last.newLen - last.oldLen
+}
+
+// editMapFromMatches はルールの置換箇所の一覧から editMap を作る
+func editMapFromMatches(matches []ruleMatch) editMap {
+	m := make(editMap, 0, len(matches))
+	delta := 0
+	for _, match := range matches {
+		m = append(m, textEdit{
+			start:  match.Start,
+			oldLen: match.End - match.Start,
+			newLen: len(match.To),
+			delta:  delta,
+		})
+		delta += len(match.To) - (match.End - match.Start)
+	}
+	return m
+}
+
+// lineIndex はテキスト内の各行の開始位置を保持し、バイトオフセットから行と桁を求める
+type lineIndex struct {
+	text       string
+	lineStarts []int
+}
+
+// newLineIndex はテキストの行頭位置を走査して lineIndex を作る
+func newLineIndex(text string) *lineIndex {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &lineIndex{text: text, lineStarts: starts}
+}
+
+// position はバイトオフセットに対応する行番号と桁番号（いずれも1始まり、桁は文字単位）を返す
+func (li *lineIndex) position(offset int) (line, column int) {
+	offset = li.clamp(offset)
+	i := li.lineOf(offset)
+	return i + 1, utf8.RuneCountInString(li.text[li.lineStarts[i]:offset]) + 1
+}
+
+// byteColumn はバイトオフセットに対応する桁番号（1始まり、バイト単位）を返す
+func (li *lineIndex) byteColumn(offset int) int {
+	offset = li.clamp(offset)
+	return offset - li.lineStarts[li.lineOf(offset)] + 1
+}
+
+// clamp はオフセットをテキストの範囲内に収める
+func (li *lineIndex) clamp(offset int) int {
+	if offset < 0 {
+		return 0
+	}
+	if offset > len(li.text) {
+		return len(li.text)
+	}
+	return offset
+}
+
+// lineOf はオフセットを含む行の番号（0始まり）を返す
+func (li *lineIndex) lineOf(offset int) int {
+	return sort.Search(len(li.lineStarts), func(i int) bool {
+		return li.lineStarts[i] > offset
+	}) - 1
+}
+
+// offsetOf は行番号（0始まり）と桁番号（1始まり、文字単位）に対応するバイトオフセットを返す
+func (li *lineIndex) offsetOf(line, column int) (int, bool) {
+	if line < 0 || line >= len(li.lineStarts) {
+		return 0, false
+	}
+	offset := li.lineStarts[line]
+	for i := 1; i < column; i++ {
+		if offset >= len(li.text) || li.text[offset] == '\n' {
+			return 0, false
+		}
+		_, size := utf8.DecodeRuneInString(li.text[offset:])
+		offset += size
+	}
+	return offset, offset < len(li.text)
+}
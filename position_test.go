@@ -0,0 +1,113 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import "testing"
+
+func TestEditMap_ToSource(t *testing.T) {
+	// "aXbc" -> "aYYYbc"（位置1の1バイトを3バイトに置換）
+	m := editMap{{start: 1, oldLen: 1, newLen: 3}}
+
+	tests := []struct {
+		name  string
+		pos   int
+		isEnd bool
+		want  int
+	}{
+		{name: "before edit", pos: 0, isEnd: false, want: 0},
+		{name: "start of edit", pos: 1, isEnd: false, want: 1},
+		{name: "inside edit as start", pos: 2, isEnd: false, want: 1},
+		{name: "inside edit as end", pos: 2, isEnd: true, want: 2},
+		{name: "end of edit", pos: 4, isEnd: true, want: 2},
+		{name: "after edit", pos: 5, isEnd: false, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.toSource(tt.pos, tt.isEnd); got != tt.want {
+				t.Errorf("toSource(%d, %v) = %d, want %d", tt.pos, tt.isEnd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditMap_ToTarget(t *testing.T) {
+	// "aXbYc" -> "aXXXbc"（位置1の1バイトを3バイトに置換し、位置3の1バイトを削除）
+	m := editMapFromMatches([]ruleMatch{
+		{Start: 1, End: 2, From: "X", To: "XXX"},
+		{Start: 3, End: 4, From: "Y", To: ""},
+	})
+
+	tests := []struct {
+		name  string
+		pos   int
+		isEnd bool
+		want  int
+	}{
+		{name: "before edit", pos: 0, isEnd: false, want: 0},
+		{name: "start of edit", pos: 1, isEnd: false, want: 1},
+		{name: "end of edit", pos: 2, isEnd: true, want: 4},
+		{name: "between edits", pos: 3, isEnd: false, want: 5},
+		{name: "inside deletion as start", pos: 4, isEnd: false, want: 5},
+		{name: "after edits", pos: 5, isEnd: false, want: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.toTarget(tt.pos, tt.isEnd); got != tt.want {
+				t.Errorf("toTarget(%d, %v) = %d, want %d", tt.pos, tt.isEnd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineIndex_Position(t *testing.T) {
+	text := "abc\nあいう\n\nxyz"
+	li := newLineIndex(text)
+
+	tests := []struct {
+		name       string
+		offset     int
+		wantLine   int
+		wantColumn int
+	}{
+		{name: "start of text", offset: 0, wantLine: 1, wantColumn: 1},
+		{name: "first line", offset: 2, wantLine: 1, wantColumn: 3},
+		{name: "multibyte line", offset: 4 + len("あい"), wantLine: 2, wantColumn: 3},
+		{name: "empty line", offset: 4 + len("あいう") + 1, wantLine: 3, wantColumn: 1},
+		{name: "last line", offset: len(text) - 1, wantLine: 4, wantColumn: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, column := li.position(tt.offset)
+			if line != tt.wantLine || column != tt.wantColumn {
+				t.Errorf("position(%d) = (%d, %d), want (%d, %d)", tt.offset, line, column, tt.wantLine, tt.wantColumn)
+			}
+		})
+	}
+}
+
+func TestLineIndex_ByteColumn(t *testing.T) {
+	li := newLineIndex("abc\nあいう")
+
+	if got := li.byteColumn(2); got != 3 {
+		t.Errorf("byteColumn(2) = %d, want 3", got)
+	}
+	// 「う」の開始位置は2行目の7バイト目
+	if got := li.byteColumn(4 + len("あい")); got != 7 {
+		t.Errorf("byteColumn(%d) = %d, want 7", 4+len("あい"), got)
+	}
+}
@@ -19,13 +19,18 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"sort"
 	"strings"
 )
 
 // Replacer はテキスト置換を行うエンジン
+//
+// Replacer は複数のゴルーチンから同時に使ってよい。置換の処理中は Config とルールを読み取るだけで、
+// 作業用の状態はすべて呼び出しごとに作成する。ただし使用中に Config やルールを変更してはならない。
 type Replacer struct {
-	config *Config
-	logger *slog.Logger
+	config     *Config
+	logger     *slog.Logger
+	rulesCache *rulesCache // フロントマターの rules で読み込んだルールファイル
 }
 
 // NewReplacer は新しいReplacerを作成する
@@ -38,19 +43,26 @@ func NewReplacer(config *Config) *Replacer {
 	slog.SetDefault(logger)
 
 	return &Replacer{
-		config: config,
-		logger: logger,
+		config:     config,
+		logger:     logger,
+		rulesCache: newRulesCache(),
 	}
 }
 
 // NewReplacerWithLogger はロガー付きの新しいReplacerを作成する
 func NewReplacerWithLogger(config *Config, logger *slog.Logger) *Replacer {
 	return &Replacer{
-		config: config,
-		logger: logger,
+		config:     config,
+		logger:     logger,
+		rulesCache: newRulesCache(),
 	}
 }
 
+// discardLogger はログを出力しないロガーを返す
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
 // ReplaceResult は置換結果を表す構造体
 type ReplaceResult struct {
 	Original string
@@ -59,13 +71,29 @@ type ReplaceResult struct {
 	Changes  []Change
 }
 
-// Change は個別の変更を表す構造体
+// Change は個別の置換箇所を表す構造体
+// 位置情報はすべて置換前の元の文書におけるもの
 type Change struct {
 	RuleIndex int
 	Rule      Rule
-	From      string
-	To        string
-	Position  int
+	From      string // 置換される元の文書のテキスト
+	To        string // 置換後のテキスト
+	// 範囲はマッチした範囲のうち置換によって実際に変わる部分で、パターンの前後の文脈（サーバ([^ー]|$) の ([^ー]|$) など）は含めない。
+	// 前のルールの置換結果にマッチした場合は、その前の変更や同じ範囲にマッチした置換箇所とまとめて、元の文書の範囲（Position から Length）の
+	// 1つの変更にする（RuleIndex と Rule は最後に適用したルール）。そのため Changes の変更の範囲は互いに重ならない
+	Position int // 置換する範囲の開始位置（バイトオフセット）
+	Length   int // 置換する範囲の長さ（バイト数）
+	Line     int // 開始位置の行番号（1始まり）
+	Column   int // 開始位置の桁番号（1始まり、文字単位）
+	// ルールがマッチしたテキスト全体（置換で変わらない部分やパターンの前後の文脈も含む）。From から To への置換を含む
+	Matched       string // マッチした元の文書のテキスト
+	Replacement   string // Matched を置換したテキスト
+	MatchPosition int    // Matched の開始位置（バイトオフセット）
+}
+
+// matchSpan は Matched の元の文書上の範囲を返す
+func (c Change) matchSpan() TextSpan {
+	return TextSpan{Start: c.MatchPosition, End: c.MatchPosition + len(c.Matched)}
 }
 
 // Replace はio.Readerから読み込んだテキストに対して全ルールを適用する
@@ -89,56 +117,167 @@ func (r *Replacer) ReplaceString(text string) *ReplaceResult {
 
 	r.logger.Info("Starting text replacement", "original_length", len(text), "rules_count", len(r.config.Rules))
 
-	// Hugoショートコードを保護
-	hugoProcessor := NewHugoProcessor()
-	protectedText, placeholders := hugoProcessor.PreserveShortcodes(text)
-	
-	if len(placeholders) > 0 {
-		r.logger.Info("Protected Hugo shortcodes", "shortcodes_count", len(placeholders))
+	// ショートコードやMarkdownのコードなど、置換の対象から外す範囲を求める
+	// テキストは書き換えず、ルールは保護されていない範囲ごとに適用する
+	// フロントマターはMarkdownとして解析しないよう空白に置き換えてから解析し、
+	// frontMatterFields に指定した項目の値を除いて保護する
+	analyzed := text
+	fm, hasFrontMatter := findFrontMatter(text)
+	if hasFrontMatter {
+		analyzed = blankFrontMatter(text, fm)
+	}
+	prot := newProtector(r.config.Markdown).protect(analyzed)
+	protected := prot.spans
+	code := prot.code
+	if hasFrontMatter {
+		fields := fm.fieldValueSpans(text, r.config.FrontMatterFields)
+		protected = subtractSpans(mergeSpans(append(protected, fm.span)), fields)
+		code = mergeSpans(append(code, fm.span))
+		r.logger.Info("Front matter detected", "format", fm.format, "length", fm.span.End, "fields_count", len(fields))
+	}
+
+	if len(protected) > 0 {
+		r.logger.Info("Protected regions", "regions_count", len(protected), "markdown", r.config.Markdown)
+	}
+
+	// in、notIn を指定したルールがある場合は、Markdownの要素ごとの範囲を求める
+	// regexp で保護する場合でも、要素の範囲は goldmark で解析して求める
+	var scopes map[string][]TextSpan
+	if r.hasScopedRules() {
+		scopes = prot.scopes
+		if scopes == nil {
+			scopes = NewGoldmarkProcessor().ScopeSpans(analyzed)
+		}
+	}
+
+	workingText := text
+
+	// 置換箇所の位置を元の文書の位置に戻すための対応表
+	// passes[k] はk番目に適用されたルールによる書き換えを表す
+	var passes []editMap
+	// これまでの変更（元の文書上の位置の順に並び、範囲は重ならない）
+	var changed []Change
+	lines := newLineIndex(text)
+
+	// grh-disable などのコメントで置換を抑止する範囲
+	// コードブロックやフロントマターなどの中にあるコメントは対象にしない
+	suppressions := findSuppressions(maskSpans(text, code))
+	if len(suppressions) > 0 {
+		r.logger.Info("Found suppression comments", "suppressions_count", len(suppressions))
+	}
+
+	// 作業中のテキスト上の位置を元の文書の位置に戻す
+	toOriginal := func(start, end int) (int, int) {
+		for k := len(passes) - 1; k >= 0; k-- {
+			start = passes[k].toSource(start, false)
+			end = passes[k].toSource(end, true)
+		}
+		return start, end
 	}
 
-	// 保護されたテキストに対してルールを適用
-	workingText := protectedText
+	// 元の文書上の位置を作業中のテキスト上の位置に移す
+	toWorking := func(start, end int) (int, int) {
+		for _, pass := range passes {
+			start = pass.toTarget(start, false)
+			end = pass.toTarget(end, true)
+		}
+		return start, end
+	}
 
 	for i, rule := range r.config.Rules {
 		if rule.compiledRegexp == nil {
 			r.logger.Warn("Rule has no compiled regexp, skipping", "rule_index", i, "expected", rule.Expected)
 			continue
 		}
+		if rule.Disabled {
+			r.logger.Debug("Rule is disabled, skipping", "rule_index", i, "expected", rule.Expected)
+			continue
+		}
 
-		// regexpMustEmptyの処理
-		if rule.RegexpMustEmpty != "" {
-			// 簡略化：regexpMustEmptyが指定されている場合はスキップ条件をチェック
-			// 実際の実装では、キャプチャグループが空でない場合はスキップする
-			r.logger.Debug("Rule has regexpMustEmpty, applying conditional logic", "rule_index", i)
+		segments := complementSpans(protected, len(workingText))
+		if rule.hasScope() {
+			segments = rule.scopeSegments(segments, scopes)
+		}
+		after, matches := rule.replaceInSegments(workingText, segments, func(start, end int) bool {
+			start, end = toOriginal(start, end)
+			return suppressions.suppressed(&rule, start, end)
+		})
+		if len(matches) == 0 {
+			continue
 		}
 
-		before := workingText
-		after := rule.ReplaceString(workingText)
+		// 置換箇所ごとに元の文書上の範囲を求め、前の変更や同じルールの他の置換箇所と重なるものは1つの変更にまとめる
+		// 前のルールの置換結果にマッチした場合は、マッチした範囲（前後の文脈を含む）と重なる前の変更も含める
+		// （前の変更を適用しない場合に、その結果を前提とする置換だけが残らないように）
+		pass := editMapFromMatches(matches)
+		groups := make([]changeGroup, 0, len(matches))
+		for k, match := range matches {
+			start, end := toOriginal(match.Start, match.End)
+			matchStart, matchEnd := toOriginal(match.MatchStart, match.MatchEnd)
+			matched := TextSpan{Start: min(start, matchStart), End: max(end, matchEnd)}
+			for _, prev := range overlappingChanges(changed, matchStart, matchEnd) {
+				start, end = min(start, prev.Position), max(end, prev.Position+prev.Length)
+				matched = matched.union(prev.matchSpan())
+			}
+			groups = append(groups, changeGroup{TextSpan: TextSpan{Start: start, End: end}, matched: matched, first: k, last: k})
+		}
+		groups, changed = mergeChanges(groups, changed)
+
+		for _, g := range groups {
+			// まとめた範囲の置換後のテキストは、作業中のテキスト上の範囲にこのルールの置換を反映した範囲
+			ws, we := toWorking(g.Start, g.End)
+			first, last := pass[g.first], pass[g.last]
+			toStart, toEnd := ws+first.delta, we+last.delta+last.newLen-last.oldLen
+			from, to := text[g.Start:g.End], after[toStart:toEnd]
+			if from == to {
+				// 後のルールが前のルールの置換を元に戻した場合は、変更として扱わない
+				continue
+			}
+			// マッチした範囲全体も同じように置換後のテキスト上の範囲に移す
+			ms, me := toWorking(g.matched.Start, g.matched.End)
+			replacementStart, replacementEnd := min(pass.toTarget(ms, false), toStart), max(pass.toTarget(me, true), toEnd)
+			line, column := lines.position(g.Start)
+			changed = append(changed, Change{
+				RuleIndex:     i,
+				Rule:          rule,
+				From:          from,
+				To:            to,
+				Position:      g.Start,
+				Length:        g.End - g.Start,
+				Line:          line,
+				Column:        column,
+				Matched:       text[g.matched.Start:g.matched.End],
+				Replacement:   after[replacementStart:replacementEnd],
+				MatchPosition: g.matched.Start,
+			})
+		}
+		sort.SliceStable(changed, func(i, j int) bool {
+			return changed[i].Position < changed[j].Position
+		})
+		passes = append(passes, pass)
+		protected = shiftSpans(protected, matches)
+		for scope, spans := range scopes {
+			scopes[scope] = shiftSpans(spans, matches)
+		}
 
-		if before != after {
+		if after != workingText {
 			workingText = after
 			result.Changed = true
-			
-			// 変更を記録（簡略化）
-			change := Change{
-				RuleIndex: i,
-				Rule:      rule,
-				From:      before,
-				To:        after,
-				Position:  0, // 簡略化：実際の位置は計算が複雑
-			}
-			result.Changes = append(result.Changes, change)
-
-			r.logger.Info("Rule applied", 
-				"rule_index", i, 
-				"expected", rule.Expected,
-				"changes_count", len(result.Changes))
 		}
+
+		r.logger.Info("Rule applied",
+			"rule_index", i,
+			"expected", rule.Expected,
+			"matches_count", len(matches),
+			"changes_count", len(changed))
 	}
 
-	// Hugoショートコードを復元
-	result.Result = hugoProcessor.RestoreShortcodes(workingText, placeholders)
+	// 変更はルールの適用順、同じルールの中では文書中の位置の順に並べる
+	sort.SliceStable(changed, func(i, j int) bool {
+		return changed[i].RuleIndex < changed[j].RuleIndex
+	})
+	result.Changes = append(result.Changes, changed...)
+	result.Result = workingText
 
 	r.logger.Info("Text replacement completed", 
 		"changed", result.Changed, 
@@ -148,6 +287,145 @@ func (r *Replacer) ReplaceString(text string) *ReplaceResult {
 	return result
 }
 
+// hasScopedRules は in、notIn を指定したルールがあるかを返す
+func (r *Replacer) hasScopedRules() bool {
+	for i := range r.config.Rules {
+		if r.config.Rules[i].hasScope() {
+			return true
+		}
+	}
+	return false
+}
+
+// rangesOverlap は2つの変更の範囲が重なるかを返す（同じ位置への挿入も重なるとみなす）
+func rangesOverlap(start, end, otherStart, otherEnd int) bool {
+	return start < otherEnd && otherStart < end || start == otherStart && (start == end || otherStart == otherEnd)
+}
+
+// changeGroup は1つの変更にまとめる元の文書上の範囲と、そこに含まれるルールの置換箇所の番号の範囲を表す
+type changeGroup struct {
+	TextSpan
+	matched TextSpan // まとめた置換箇所と前の変更がマッチした範囲全体
+	first   int
+	last    int
+}
+
+// overlappingChanges は changed（位置の順に並び、重ならない）のうち start から end までの範囲と重なるものを返す
+func overlappingChanges(changed []Change, start, end int) []Change {
+	i := sort.Search(len(changed), func(i int) bool {
+		return changed[i].Position+changed[i].Length > start
+	})
+	j := i
+	for j < len(changed) && changed[j].Position < end {
+		j++
+	}
+	return changed[i:j]
+}
+
+// mergeChanges は groups のうち互いに重なるもの、前の変更 changed（位置の順に並び、重ならない）と重なるものをまとめる
+// まとめた範囲の一覧（位置の順）と、どの範囲とも重ならずにそのまま残る前の変更を返す
+func mergeChanges(groups []changeGroup, changed []Change) ([]changeGroup, []Change) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Start < groups[j].Start
+	})
+
+	merged := make([]changeGroup, 0, len(groups))
+	kept := make([]Change, 0, len(changed))
+	// 範囲を開始位置の順に調べ、直前にまとめた範囲と重なる前の変更はその範囲に含める
+	addChange := func(c Change) {
+		if n := len(merged); n > 0 && rangesOverlap(merged[n-1].Start, merged[n-1].End, c.Position, c.Position+c.Length) {
+			merged[n-1].End = max(merged[n-1].End, c.Position+c.Length)
+			merged[n-1].matched = merged[n-1].matched.union(c.matchSpan())
+			return
+		}
+		kept = append(kept, c)
+	}
+	addGroup := func(g changeGroup) {
+		// 前の変更は重ならないため、g と重なりうるのは直前に残した前の変更だけ
+		if n := len(kept); n > 0 && rangesOverlap(kept[n-1].Position, kept[n-1].Position+kept[n-1].Length, g.Start, g.End) {
+			g.Start = min(g.Start, kept[n-1].Position)
+			g.End = max(g.End, kept[n-1].Position+kept[n-1].Length)
+			g.matched = g.matched.union(kept[n-1].matchSpan())
+			kept = kept[:n-1]
+		}
+		if n := len(merged); n > 0 && rangesOverlap(merged[n-1].Start, merged[n-1].End, g.Start, g.End) {
+			last := &merged[n-1]
+			last.Start, last.End = min(last.Start, g.Start), max(last.End, g.End)
+			last.matched = last.matched.union(g.matched)
+			last.first, last.last = min(last.first, g.first), max(last.last, g.last)
+			return
+		}
+		merged = append(merged, g)
+	}
+
+	i := 0
+	for _, g := range groups {
+		for ; i < len(changed) && changed[i].Position <= g.Start; i++ {
+			addChange(changed[i])
+		}
+		addGroup(g)
+	}
+	for ; i < len(changed); i++ {
+		addChange(changed[i])
+	}
+	return merged, kept
+}
+
+// ApplyChanges は元の文書に changes の変更だけを適用した結果を返す
+// 一部の置換箇所だけを採用したり、置換後のテキスト（To）を書き換えたりする場合に使う。
+// Changes の変更は範囲が重ならないため、どの組み合わせでも適用できる。範囲が重なる変更を渡した場合はエラーになる。
+func (result *ReplaceResult) ApplyChanges(changes []Change) (*ReplaceResult, error) {
+	applied := make([]Change, len(changes))
+	copy(applied, changes)
+	sort.SliceStable(applied, func(i, j int) bool {
+		return applied[i].Position < applied[j].Position
+	})
+
+	var sb strings.Builder
+	last := 0
+	for i, change := range applied {
+		start, end := change.Position, change.Position+change.Length
+		if start < 0 || end > len(result.Original) {
+			return nil, fmt.Errorf("change at offset %d is out of range", change.Position)
+		}
+		if i > 0 {
+			prev := applied[i-1]
+			if rangesOverlap(prev.Position, prev.Position+prev.Length, start, end) {
+				return nil, fmt.Errorf("change at line %d, column %d overlaps another change", change.Line, change.Column)
+			}
+		}
+		sb.WriteString(result.Original[last:start])
+		sb.WriteString(change.To)
+		last = end
+	}
+	sb.WriteString(result.Original[last:])
+
+	text := sb.String()
+	return &ReplaceResult{
+		Original: result.Original,
+		Result:   text,
+		Changed:  text != result.Original,
+		Changes:  changes,
+	}, nil
+}
+
+// EditChange は change の Matched 全体を text に置き換える変更を返す
+// 置換後のテキストを書き換えて適用する場合に使う。範囲は Matched のうち text と異なる部分に狭める
+func (result *ReplaceResult) EditChange(change Change, text string) Change {
+	m := trimUnchanged(ruleMatch{
+		Start: change.MatchPosition,
+		End:   change.MatchPosition + len(change.Matched),
+		From:  change.Matched,
+		To:    text,
+	})
+	line, column := newLineIndex(result.Original).position(m.Start)
+	change.From, change.To = m.From, m.To
+	change.Position, change.Length = m.Start, m.End-m.Start
+	change.Line, change.Column = line, column
+	change.Replacement = text
+	return change
+}
+
 // ReplaceFile はファイルに対して置換を行う
 func (r *Replacer) ReplaceFile(filePath string) (*ReplaceResult, error) {
 	file, err := os.Open(filePath)
@@ -177,48 +455,23 @@ func (r *Replacer) WriteResult(result *ReplaceResult, filePath string) error {
 }
 
 // GenerateDiff は置換前後の差分をUnified diff形式で生成する
+// 出力は DefaultDiffOptions の設定で、patch -p1 や git apply でそのまま適用できる
 func (r *Replacer) GenerateDiff(result *ReplaceResult, filename string) string {
+	return r.GenerateDiffWithOptions(result, filename, DefaultDiffOptions())
+}
+
+// GenerateDiffWithOptions は置換前後の差分を指定した設定で生成する
+// Granularity に DiffWord、DiffChar を指定した場合は、行内の変更箇所だけを強調した差分を生成する
+func (r *Replacer) GenerateDiffWithOptions(result *ReplaceResult, filename string, opts DiffOptions) string {
 	if !result.Changed {
 		return ""
 	}
 
-	// 簡略化されたdiff生成（実際にはより複雑な実装が必要）
-	var diff strings.Builder
-	
-	diff.WriteString(fmt.Sprintf("--- %s\n", filename))
-	diff.WriteString(fmt.Sprintf("+++ %s\n", filename))
-	diff.WriteString("@@ -1,1 +1,1 @@\n")
-	
-	// 行ごとの差分を生成（簡略化）
-	originalLines := strings.Split(result.Original, "\n")
-	resultLines := strings.Split(result.Result, "\n")
-	
-	maxLines := len(originalLines)
-	if len(resultLines) > maxLines {
-		maxLines = len(resultLines)
+	oldName, newName := opts.OldPrefix+filename, opts.NewPrefix+filename
+	if opts.Granularity != DiffLine {
+		return inlineDiff(result.Original, result.Result, oldName, newName, opts)
 	}
-	
-	for i := 0; i < maxLines; i++ {
-		var originalLine, resultLine string
-		
-		if i < len(originalLines) {
-			originalLine = originalLines[i]
-		}
-		if i < len(resultLines) {
-			resultLine = resultLines[i]
-		}
-		
-		if originalLine != resultLine {
-			if originalLine != "" {
-				diff.WriteString(fmt.Sprintf("-%s\n", originalLine))
-			}
-			if resultLine != "" {
-				diff.WriteString(fmt.Sprintf("+%s\n", resultLine))
-			}
-		}
-	}
-	
-	return diff.String()
+	return unifiedDiff(result.Original, result.Result, oldName, newName, opts.Context)
 }
 
 // ValidateMarkdown はMarkdownファイルの妥当性を検証する（Hugoショートコード対応）
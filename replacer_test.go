@@ -17,6 +17,7 @@ package grh
 import (
 	"log/slog"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -163,6 +164,18 @@ func TestReplacer_GenerateDiff(t *testing.T) {
 	if !strings.Contains(diff, "---") || !strings.Contains(diff, "+++") {
 		t.Error("Expected diff to contain unified diff headers")
 	}
+
+	expected := "--- a/test.txt\n+++ b/test.txt\n@@ -1 +1 @@\n-This is a test\n\\ No newline at end of file\n+This is a Test\n\\ No newline at end of file\n"
+	if diff != expected {
+		t.Errorf("GenerateDiff() =\n%s\nwant\n%s", diff, expected)
+	}
+
+	// コンテキスト行数とプレフィックスを指定する
+	opts := DiffOptions{Context: 0, OldPrefix: "", NewPrefix: ""}
+	diff = replacer.GenerateDiffWithOptions(result, "test.txt", opts)
+	if !strings.HasPrefix(diff, "--- test.txt\n+++ test.txt\n") {
+		t.Errorf("GenerateDiffWithOptions() headers = %q", diff)
+	}
 }
 
 func TestReplacer_ValidateMarkdown(t *testing.T) {
@@ -257,3 +270,508 @@ func TestReplacer_ReplaceString_WithIgnorePatternBefore(t *testing.T) {
 		})
 	}
 }
+
+func TestReplacer_ReplaceString_WithRegexpMustEmpty(t *testing.T) {
+	config := &Config{
+		Rules: []Rule{
+			{
+				Expected:        "ソフトウェア",
+				Pattern:         "(日経)?ソフトウエア",
+				RegexpMustEmpty: "$1",
+			},
+		},
+	}
+
+	for i := range config.Rules {
+		if err := config.Rules[i].CompilePattern(); err != nil {
+			t.Fatalf("Failed to compile rule %d: %v", i, err)
+		}
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	replacer := NewReplacerWithLogger(config, logger)
+
+	input := "日経ソフトウエアで広義のソフトウエアを学ぶ"
+	expected := "日経ソフトウエアで広義のソフトウェアを学ぶ"
+	result := replacer.ReplaceString(input)
+	if result.Result != expected {
+		t.Errorf("ReplaceString() = %q, want %q", result.Result, expected)
+	}
+
+	// 日経ソフトウエアしか含まない場合は変更されない
+	result = replacer.ReplaceString("日経ソフトウエア")
+	if result.Changed {
+		t.Errorf("ReplaceString() should not change text, got %q", result.Result)
+	}
+}
+
+func TestReplacer_ReplaceString_ChangePositions(t *testing.T) {
+	config := &Config{
+		Rules: []Rule{
+			{Expected: "Cookie", Pattern: "[Cc]ookie"},
+			{Expected: "ハードウェア", Patterns: []string{"ハードウエアー", "ハードウエア"}},
+		},
+	}
+
+	for i := range config.Rules {
+		if err := config.Rules[i].CompilePattern(); err != nil {
+			t.Fatalf("Failed to compile rule %d: %v", i, err)
+		}
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	replacer := NewReplacerWithLogger(config, logger)
+
+	input := "cookieと`cookie`と{{< note >}}cookie{{< /note >}}とcookie\nハードウエアーとcookie"
+	result := replacer.ReplaceString(input)
+
+	expected := "Cookieと`cookie`と{{< note >}}cookie{{< /note >}}とCookie\nハードウェアとCookie"
+	if result.Result != expected {
+		t.Fatalf("ReplaceString() = %q, want %q", result.Result, expected)
+	}
+
+	wants := []struct {
+		ruleIndex int
+		from      string
+		to        string
+		line      int
+		column    int
+	}{
+		{ruleIndex: 0, from: "c", to: "C", line: 1, column: 1},
+		{ruleIndex: 0, from: "c", to: "C", line: 1, column: 49},
+		{ruleIndex: 0, from: "c", to: "C", line: 2, column: 9},
+		{ruleIndex: 1, from: "エアー", to: "ェア", line: 2, column: 5},
+	}
+
+	if len(result.Changes) != len(wants) {
+		t.Fatalf("len(Changes) = %d, want %d", len(result.Changes), len(wants))
+	}
+
+	for i, want := range wants {
+		change := result.Changes[i]
+		if change.RuleIndex != want.ruleIndex || change.From != want.from || change.To != want.to {
+			t.Errorf("Changes[%d] = {%d %q %q}, want {%d %q %q}", i, change.RuleIndex, change.From, change.To, want.ruleIndex, want.from, want.to)
+		}
+		if change.Line != want.line || change.Column != want.column {
+			t.Errorf("Changes[%d] position = %d:%d, want %d:%d", i, change.Line, change.Column, want.line, want.column)
+		}
+		// 位置と長さは元の文書上のマッチした範囲のうち置換で変わる部分を指す
+		if got := input[change.Position : change.Position+change.Length]; got != want.from {
+			t.Errorf("Changes[%d] original range = %q, want %q", i, got, want.from)
+		}
+	}
+}
+
+func TestReplacer_ReplaceString_TrimsUnchangedContext(t *testing.T) {
+	// パターンの前後の文脈（([^ー]|$) の改行）は置換箇所に含めない
+	config := &Config{Rules: []Rule{{Expected: "サーバー$1", Pattern: "サーバ([^ー]|$)"}}}
+	if err := config.Rules[0].CompilePattern(); err != nil {
+		t.Fatalf("Failed to compile rule: %v", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	input := "サーバ\nfoo\n"
+	result := NewReplacerWithLogger(config, logger).ReplaceString(input)
+	if len(result.Changes) != 1 {
+		t.Fatalf("Changes = %+v, want 1 change", result.Changes)
+	}
+
+	// 挿入するだけの置換は直前の1文字を含める
+	change := result.Changes[0]
+	if change.From != "バ" || change.To != "バー" || change.Line != 1 || change.Column != 3 {
+		t.Errorf("Change = {%q %q %d:%d}, want {\"バ\" \"バー\" 1:3}", change.From, change.To, change.Line, change.Column)
+	}
+	if got := input[change.Position : change.Position+change.Length]; got != change.From {
+		t.Errorf("original range = %q, want %q", got, change.From)
+	}
+
+	// マッチしたテキスト全体は前後の文脈も含めて残す
+	if change.Matched != "サーバ\n" || change.Replacement != "サーバー\n" || change.MatchPosition != 0 {
+		t.Errorf("Matched = %q -> %q at %d, want \"サーバ\\n\" -> \"サーバー\\n\" at 0", change.Matched, change.Replacement, change.MatchPosition)
+	}
+}
+
+func TestReplaceResult_EditChange(t *testing.T) {
+	config := &Config{Rules: []Rule{{Expected: "サーバー$1", Pattern: "サーバ([^ー]|$)"}}}
+	if err := config.Rules[0].CompilePattern(); err != nil {
+		t.Fatalf("Failed to compile rule: %v", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	result := NewReplacerWithLogger(config, logger).ReplaceString("foo サーバ\n")
+	if len(result.Changes) != 1 {
+		t.Fatalf("Changes = %+v, want 1 change", result.Changes)
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		change Change // From、To、Position、Length、Column だけを比較する
+		want   string
+	}{
+		{
+			// 範囲はマッチしたテキストのうち入力したテキストと異なる部分に狭める
+			name:   "edit",
+			text:   "サーバ!\n",
+			change: Change{From: "バ", To: "バ!", Position: 10, Length: 3, Column: 7},
+			want:   "foo サーバ!\n",
+		},
+		{
+			name:   "delete",
+			text:   "\n",
+			change: Change{From: "サーバ", To: "", Position: 4, Length: 9, Column: 5},
+			want:   "foo \n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited := result.EditChange(result.Changes[0], tt.text)
+			got := Change{From: edited.From, To: edited.To, Position: edited.Position, Length: edited.Length, Column: edited.Column}
+			if !reflect.DeepEqual(got, tt.change) {
+				t.Errorf("EditChange() = %+v, want %+v", got, tt.change)
+			}
+			if edited.Replacement != tt.text {
+				t.Errorf("Replacement = %q, want %q", edited.Replacement, tt.text)
+			}
+			applied, err := result.ApplyChanges([]Change{edited})
+			if err != nil {
+				t.Fatalf("ApplyChanges() error = %v", err)
+			}
+			if applied.Result != tt.want {
+				t.Errorf("ApplyChanges() = %q, want %q", applied.Result, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplacer_ReplaceString_ProtectedRegions(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []Rule
+		input    string
+		expected string
+	}{
+		{
+			// 以前はプレースホルダーの文字列（___MARKDOWN_CODE_SPAN_1___ など）がルールにマッチして壊れていた
+			name:     "rules matching placeholder-like text",
+			rules:    []Rule{{Expected: "コード", Pattern: "CODE"}, {Expected: "-", Pattern: "_+"}},
+			input:    "CODE and `x` and {{< note >}}CODE{{< /note >}} and a__b",
+			expected: "コード and `x` and {{< note >}}CODE{{< /note >}} and a-b",
+		},
+		{
+			name:     "document containing placeholder-like text",
+			rules:    []Rule{{Expected: "Cookie", Pattern: "[Cc]ookie"}},
+			input:    "___MARKDOWN_CODE_SPAN_1___ cookie `cookie`",
+			expected: "___MARKDOWN_CODE_SPAN_1___ Cookie `cookie`",
+		},
+		{
+			name:     "match crossing a protected boundary is rejected",
+			rules:    []Rule{{Expected: "Go言語", Pattern: "Go `言語"}},
+			input:    "Go `言語` と Go `言語`",
+			expected: "Go `言語` と Go `言語`",
+		},
+		{
+			name:     "end of segment before protected region",
+			rules:    []Rule{{Expected: "サーバー$1", Pattern: "サーバ([^ー]|$)"}},
+			input:    "サーバ`x`とサーバ",
+			expected: "サーバー`x`とサーバー",
+		},
+		{
+			// 以前は保護された範囲ごとに正規表現を実行していたため、^ がコードスパンの直後にもマッチしていた
+			name:     "line start anchor does not match after protected region",
+			rules:    []Rule{{Expected: "* ", Pattern: "(?m)^- "}},
+			input:    "- item `code`- x\n- `y`- z",
+			expected: "* item `code`- x\n* `y`- z",
+		},
+		{
+			name:     "line end anchor does not match before protected region",
+			rules:    []Rule{{Expected: "。", Pattern: "(?m)\\.$"}},
+			input:    "see `x`. and `y`.\nend.",
+			expected: "see `x`. and `y`。\nend。",
+		},
+		{
+			name:     "trailing context inside protected region is kept",
+			rules:    []Rule{{Expected: "サーバー$1", Pattern: "サーバ(.)"}},
+			input:    "サーバ`x` サーバ`ー`",
+			expected: "サーバー`x` サーバー`ー`",
+		},
+		{
+			name:     "ignorePatternBefore sees text before protected region",
+			rules:    []Rule{{Expected: "Cookie", Pattern: "[Cc]ookie", IgnorePatternBefore: "`x` $"}},
+			input:    "`x` cookie, `y` cookie",
+			expected: "`x` cookie, `y` Cookie",
+		},
+		{
+			name:     "earlier rules shift protected regions",
+			rules:    []Rule{{Expected: "ハードウェア", Pattern: "ハードウエアー"}, {Expected: "Cookie", Pattern: "[Cc]ookie"}},
+			input:    "ハードウエアー `cookie` cookie",
+			expected: "ハードウェア `cookie` Cookie",
+		},
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Rules: tt.rules}
+			for i := range config.Rules {
+				if err := config.Rules[i].CompilePattern(); err != nil {
+					t.Fatalf("Failed to compile rule %d: %v", i, err)
+				}
+			}
+
+			result := NewReplacerWithLogger(config, logger).ReplaceString(tt.input)
+			if result.Result != tt.expected {
+				t.Errorf("ReplaceString() = %q, want %q", result.Result, tt.expected)
+			}
+			for _, change := range result.Changes {
+				if got := tt.input[change.Position : change.Position+change.Length]; got != change.From {
+					t.Errorf("Change %+v points to %q in the original", change, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReplaceResult_ApplyChanges(t *testing.T) {
+	config := &Config{
+		Rules: []Rule{
+			{Expected: "Cookie", Pattern: "[Cc]ookie"},
+			{Expected: "JQuery", Pattern: "jquery"},
+			{Expected: "jQuery", Pattern: "JQuery"},
+		},
+	}
+
+	for i := range config.Rules {
+		if err := config.Rules[i].CompilePattern(); err != nil {
+			t.Fatalf("Failed to compile rule %d: %v", i, err)
+		}
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	replacer := NewReplacerWithLogger(config, logger)
+
+	input := "cookie and jquery and cookie"
+	result := replacer.ReplaceString(input)
+	// jquery -> JQuery -> jQuery の置換は1つの変更にまとめる
+	if len(result.Changes) != 3 {
+		t.Fatalf("len(Changes) = %d, want 3", len(result.Changes))
+	}
+
+	// すべての変更を適用するとReplaceStringの結果と同じになる
+	all, err := result.ApplyChanges(result.Changes)
+	if err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+	if all.Result != result.Result {
+		t.Errorf("ApplyChanges(all) = %q, want %q", all.Result, result.Result)
+	}
+
+	// 2つ目のcookieを採用せず、1つ目の置換後のテキストを書き換える
+	first := result.Changes[0]
+	first.To = "K"
+	partial, err := result.ApplyChanges([]Change{first, result.Changes[2]})
+	if err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+	if want := "Kookie and jQuery and cookie"; partial.Result != want {
+		t.Errorf("ApplyChanges(partial) = %q, want %q", partial.Result, want)
+	}
+
+	// 変更を1つも適用しない場合は元の文書のまま
+	none, err := result.ApplyChanges(nil)
+	if err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+	if none.Changed || none.Result != input {
+		t.Errorf("ApplyChanges(nil) = %q (changed: %v), want the original", none.Result, none.Changed)
+	}
+
+	// 範囲が重なる変更はエラー
+	wide := Change{To: "CO", Position: 0, Length: 2}
+	overlap := Change{To: "x", Position: 1, Length: 2}
+	if _, err := result.ApplyChanges([]Change{wide, overlap}); err == nil {
+		t.Error("ApplyChanges() should fail for partially overlapping changes")
+	}
+}
+
+func TestReplaceResult_ApplyChanges_Chained(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rule
+		input   string
+		want    string
+		changes []Change
+		matched []string // Matched -> Replacement
+	}{
+		{
+			// 後のルールが前のルールの置換結果の一部だけにマッチする場合は、元の文書の範囲全体の変更にまとめる
+			name:    "partial match of an earlier replacement",
+			rules:   []Rule{{Expected: "bc", Pattern: "a"}, {Expected: "X", Pattern: "cd"}},
+			input:   "ad",
+			want:    "bX",
+			changes: []Change{{From: "ad", To: "bX", Position: 0, Length: 2}},
+			matched: []string{"ad -> bX"},
+		},
+		{
+			// 同じルールが前の置換結果の中に複数回マッチする場合は、前の変更とすべての置換を1つの変更にまとめる
+			name:    "several matches in an earlier replacement",
+			rules:   []Rule{{Expected: "bcbc", Pattern: "a"}, {Expected: "X", Pattern: "b"}},
+			input:   "a",
+			want:    "XcXc",
+			changes: []Change{{From: "a", To: "XcXc", Position: 0, Length: 1}},
+			matched: []string{"a -> XcXc"},
+		},
+		{
+			// 前の置換結果の中の複数の置換箇所が、元の文書の前後のテキストにもまたがる
+			name:    "matches spanning an earlier replacement and its context",
+			rules:   []Rule{{Expected: "ｂｂ", Pattern: "B"}, {Expected: "c", Pattern: "a?ｂ"}},
+			input:   "aB\n",
+			want:    "cc\n",
+			changes: []Change{{From: "aB", To: "cc", Position: 0, Length: 2}},
+			matched: []string{"aB -> cc"},
+		},
+		{
+			name:    "word replaced twice by a later rule",
+			rules:   []Rule{{Expected: "JavaScript", Pattern: "\\bJS\\b"}, {Expected: "ａ", Pattern: "a"}},
+			input:   "JS and a\n",
+			want:    "JａvａScript ａnd ａ\n",
+			changes: []Change{{From: "S", To: "ａvａScript", Position: 1, Length: 1}, {From: "a", To: "ａ", Position: 3, Length: 1}, {From: "a", To: "ａ", Position: 7, Length: 1}},
+			matched: []string{"JS -> JａvａScript", "a -> ａ", "a -> ａ"},
+		},
+		{
+			// マッチしたテキストは、前の変更がマッチした前後の文脈（改行）も含める
+			name:    "chained match with context",
+			rules:   []Rule{{Expected: "サーバー$1", Pattern: "サーバ([^ー]|$)"}, {Expected: "Webサーバー", Pattern: "WEBサーバー"}},
+			input:   "WEBサーバ\n",
+			want:    "Webサーバー\n",
+			changes: []Change{{From: "EBサーバ", To: "ebサーバー", Position: 1, Length: 11}},
+			matched: []string{"WEBサーバ\n -> Webサーバー\n"},
+		},
+		{
+			// 後のルールが前のルールの置換を元に戻した場合は、変更として扱わない
+			name:    "later rule reverts an earlier replacement",
+			rules:   []Rule{{Expected: "b", Pattern: "a"}, {Expected: "a", Pattern: "b"}},
+			input:   "a c",
+			want:    "a c",
+			changes: nil,
+		},
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Rules: tt.rules}
+			for i := range config.Rules {
+				if err := config.Rules[i].CompilePattern(); err != nil {
+					t.Fatalf("Failed to compile rule %d: %v", i, err)
+				}
+			}
+
+			result := NewReplacerWithLogger(config, logger).ReplaceString(tt.input)
+			if result.Result != tt.want {
+				t.Fatalf("ReplaceString() = %q, want %q", result.Result, tt.want)
+			}
+			var got []Change
+			for _, change := range result.Changes {
+				got = append(got, Change{From: change.From, To: change.To, Position: change.Position, Length: change.Length})
+				if matched := tt.input[change.MatchPosition : change.MatchPosition+len(change.Matched)]; matched != change.Matched {
+					t.Errorf("Matched = %q, but the original has %q", change.Matched, matched)
+				}
+			}
+			var matched []string
+			for _, change := range result.Changes {
+				matched = append(matched, change.Matched+" -> "+change.Replacement)
+			}
+			if !reflect.DeepEqual(matched, tt.matched) {
+				t.Errorf("Matched = %q, want %q", matched, tt.matched)
+			}
+			if !reflect.DeepEqual(got, tt.changes) {
+				t.Errorf("Changes = %+v, want %+v", got, tt.changes)
+			}
+
+			// すべての変更を適用した結果と、各変更を元の文書に単独で適用した結果（レポートの修正案）が一致する
+			all, err := result.ApplyChanges(result.Changes)
+			if err != nil {
+				t.Fatalf("ApplyChanges() error = %v", err)
+			}
+			if all.Result != tt.want {
+				t.Errorf("ApplyChanges(all) = %q, want %q", all.Result, tt.want)
+			}
+
+			// 変更の範囲は重ならないため、1つだけ適用した結果も元の文書のその範囲だけが変わる
+			for _, change := range result.Changes {
+				single, err := result.ApplyChanges([]Change{change})
+				if err != nil {
+					t.Fatalf("ApplyChanges(%+v) error = %v", change, err)
+				}
+				if want := tt.input[:change.Position] + change.To + tt.input[change.Position+change.Length:]; single.Result != want {
+					t.Errorf("ApplyChanges(%+v) = %q, want %q", change, single.Result, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReplacer_ConcurrentUse(t *testing.T) {
+	config, err := LoadConfig("testdata/yaml/complex.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	replacer := NewReplacerWithLogger(config, logger)
+
+	inputs := []string{
+		"I love javascript and api",
+		"データーベースとDBの設計",
+		"コンピュータの性能 {{< note >}}javascript{{< /note >}}",
+		"`api` と api",
+	}
+
+	// 逐次実行した結果を期待値とする
+	expected := make([]*ReplaceResult, len(inputs))
+	for i, input := range inputs {
+		expected[i] = replacer.ReplaceString(input)
+	}
+
+	const goroutines = 16
+	errs := make(chan string, goroutines*len(inputs))
+	done := make(chan struct{})
+	for g := 0; g < goroutines; g++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i, input := range inputs {
+				result := replacer.ReplaceString(input)
+				if result.Result != expected[i].Result || len(result.Changes) != len(expected[i].Changes) {
+					errs <- result.Result
+				}
+			}
+		}()
+	}
+	for g := 0; g < goroutines; g++ {
+		<-done
+	}
+	close(errs)
+
+	for result := range errs {
+		t.Errorf("concurrent ReplaceString() produced a different result: %q", result)
+	}
+}
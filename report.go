@@ -0,0 +1,559 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Finding はルールに該当した1箇所を表す構造体
+// 位置情報はすべて置換前の元の文書におけるもの
+// Matched と Replacement はルールがマッチしたテキスト全体で、Range はそのうち置換で実際に変わる部分を指す
+type Finding struct {
+	File        string       `json:"file"`
+	Rule        FindingRule  `json:"rule"`
+	Matched     string       `json:"matched"`     // ルールがマッチしたテキスト（パターンの前後の文脈も含む）
+	Replacement string       `json:"replacement"` // Matched を置換したテキスト
+	Range       FindingRange `json:"range"`
+	Suggestion  string       `json:"suggestion"` // Range の範囲を置き換えるテキスト（修正案）
+}
+
+// FindingRule は該当したルールの情報を表す構造体
+type FindingRule struct {
+	ID       string `json:"id"`
+	Index    int    `json:"index"`
+	Expected string `json:"expected"`
+	Pattern  string `json:"pattern,omitempty"`
+	Source   string `json:"source,omitempty"` // ルールが定義されていたルールファイルのパス
+	Line     int    `json:"line,omitempty"`   // ルールファイル内でルールが定義されていた行（1始まり）
+	Column   int    `json:"column,omitempty"` // ルールファイル内でルールが定義されていた桁（1始まり）
+}
+
+// SourcePosition はルールが定義されていたルールファイル内の位置を返す
+func (fr FindingRule) SourcePosition() SourcePosition {
+	return SourcePosition{Path: fr.Source, Line: fr.Line, Column: fr.Column}
+}
+
+// FindingRange は該当した範囲を表す構造体（Endは範囲の直後の位置）
+type FindingRange struct {
+	Start FindingPosition `json:"start"`
+	End   FindingPosition `json:"end"`
+}
+
+// FindingPosition は文書中の位置を表す構造体
+type FindingPosition struct {
+	Offset     int `json:"offset"`     // バイトオフセット
+	Line       int `json:"line"`       // 行番号（1始まり）
+	Column     int `json:"column"`     // 桁番号（1始まり、文字単位）
+	ByteColumn int `json:"byteColumn"` // 桁番号（1始まり、UTF-8のバイト単位）
+}
+
+// Message は該当箇所を説明する1行のメッセージを返す
+func (f Finding) Message() string {
+	return fmt.Sprintf("%q -> %q", f.Matched, f.Replacement)
+}
+
+// NewFindings は置換結果からファイル内の出現順に並んだ Finding の一覧を作る
+func NewFindings(filePath string, result *ReplaceResult) []Finding {
+	lines := newLineIndex(result.Original)
+	findings := make([]Finding, 0, len(result.Changes))
+
+	for _, change := range result.Changes {
+		endOffset := change.Position + change.Length
+		endLine, endColumn := lines.position(endOffset)
+		findings = append(findings, Finding{
+			File: filePath,
+			Rule: FindingRule{
+				ID:       change.Rule.id(),
+				Index:    change.RuleIndex,
+				Expected: change.Rule.Expected,
+				Pattern:  change.Rule.CompiledPattern(),
+				Source:   change.Rule.Source().Path,
+				Line:     change.Rule.Source().Line,
+				Column:   change.Rule.Source().Column,
+			},
+			Matched:     change.Matched,
+			Replacement: change.Replacement,
+			Suggestion:  change.To,
+			Range: FindingRange{
+				Start: FindingPosition{
+					Offset:     change.Position,
+					Line:       change.Line,
+					Column:     change.Column,
+					ByteColumn: lines.byteColumn(change.Position),
+				},
+				End: FindingPosition{
+					Offset:     endOffset,
+					Line:       endLine,
+					Column:     endColumn,
+					ByteColumn: lines.byteColumn(endOffset),
+				},
+			},
+		})
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Range.Start.Offset < findings[j].Range.Start.Offset
+	})
+
+	// 同じ範囲に同じ修正案を示す Finding は1件にまとめる
+	unique := findings[:0]
+	for _, f := range findings {
+		if n := len(unique); n > 0 && unique[n-1].Range == f.Range && unique[n-1].Suggestion == f.Suggestion {
+			continue
+		}
+		unique = append(unique, f)
+	}
+	return unique
+}
+
+// fixableFindings は各 Finding に修正案を付けるかを返す
+// 修正案をすべて適用しても文書が壊れないよう、同じファイルで前の Finding の範囲と重なるものには付けない
+// （NewFindings で作った Finding は Change の範囲が重ならないため、すべてに付ける）
+func fixableFindings(findings []Finding) []bool {
+	order := make([]int, len(findings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := findings[order[i]], findings[order[j]]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Range.Start.Offset < b.Range.Start.Offset
+	})
+
+	fixable := make([]bool, len(findings))
+	var last *Finding
+	for _, i := range order {
+		f := &findings[i]
+		if last != nil && last.File == f.File &&
+			rangesOverlap(last.Range.Start.Offset, last.Range.End.Offset, f.Range.Start.Offset, f.Range.End.Offset) {
+			continue
+		}
+		fixable[i] = true
+		last = f
+	}
+	return fixable
+}
+
+// findingsWriters は出力形式の名前と Finding の一覧を書き出す関数の対応
+var findingsWriters = map[string]func(io.Writer, []Finding) error{
+	"text":       writeFindingsText,
+	"jsonl":      writeFindingsJSONL,
+	"sarif":      writeFindingsSARIF,
+	"checkstyle": writeFindingsCheckstyle,
+	"rdjson":     writeFindingsRDJSON,
+	"rdjsonl":    writeFindingsRDJSONL,
+	"github":     writeFindingsGitHub,
+}
+
+// FindingsFormats は WriteFindings が対応している出力形式の名前を返す
+func FindingsFormats() []string {
+	formats := make([]string, 0, len(findingsWriters))
+	for name := range findingsWriters {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// WriteFindings は Finding の一覧を指定した形式で書き出す
+func WriteFindings(w io.Writer, format string, findings []Finding) error {
+	writer, ok := findingsWriters[format]
+	if !ok {
+		return fmt.Errorf("unknown findings format %q", format)
+	}
+	return writer(w, findings)
+}
+
+// writeFindingsText は「ファイル名:行:桁: メッセージ」の形式で1件1行ずつ書き出す
+func writeFindingsText(w io.Writer, findings []Finding) error {
+	for _, f := range findings {
+		_, err := fmt.Fprintf(w, "%s:%d:%d: %s (%s: %s)\n",
+			f.File, f.Range.Start.Line, f.Range.Start.Column,
+			f.Message(), f.Rule.ID, f.Rule.Expected)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFindingsJSONL はJSON Lines形式で1件1行ずつ書き出す
+func writeFindingsJSONL(w io.Writer, findings []Finding) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, f := range findings {
+		if err := encoder.Encode(f); err != nil {
+			return fmt.Errorf("failed to encode finding: %w", err)
+		}
+	}
+	return nil
+}
+
+// SARIF 2.1.0 の出力に必要な部分だけを表す構造体
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	Properties       *sarifProps  `json:"properties,omitempty"`
+}
+
+type sarifProps struct {
+	Pattern string `json:"pattern,omitempty"`
+	Source  string `json:"source,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+	Fixes     []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+// writeFindingsSARIF はSARIF 2.1.0形式で書き出す
+// 桁番号は文字単位のため columnKind に unicodeCodePoints を指定する
+func writeFindingsSARIF(w io.Writer, findings []Finding) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "grh",
+			InformationURI: "https://github.com/ymotongpoo/grh",
+			Rules:          []sarifRule{},
+		}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+
+	ruleIndexes := make(map[string]int)
+	fixable := fixableFindings(findings)
+	for i, f := range findings {
+		id := f.Rule.ID
+		index, ok := ruleIndexes[id]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndexes[id] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               id,
+				ShortDescription: sarifMessage{Text: f.Rule.Expected},
+			})
+			if f.Rule.Pattern != "" || f.Rule.Source != "" {
+				run.Tool.Driver.Rules[index].Properties = &sarifProps{Pattern: f.Rule.Pattern, Source: f.Rule.SourcePosition().String()}
+			}
+		}
+
+		region := sarifRegion{
+			StartLine:   f.Range.Start.Line,
+			StartColumn: f.Range.Start.Column,
+			EndLine:     f.Range.End.Line,
+			EndColumn:   f.Range.End.Column,
+		}
+		location := sarifArtifactLocation{URI: f.File}
+		result := sarifResult{
+			RuleID:    id,
+			RuleIndex: index,
+			Level:     "warning",
+			Message:   sarifMessage{Text: f.Message()},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: location,
+				Region:           region,
+			}}},
+		}
+		if fixable[i] {
+			result.Fixes = []sarifFix{{
+				Description: sarifMessage{Text: fmt.Sprintf("Replace with %q", f.Suggestion)},
+				ArtifactChanges: []sarifArtifactChange{{
+					ArtifactLocation: location,
+					Replacements: []sarifReplacement{{
+						DeletedRegion:   region,
+						InsertedContent: sarifMessage{Text: f.Suggestion},
+					}},
+				}},
+			}}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	log := sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(log); err != nil {
+		return fmt.Errorf("failed to encode SARIF: %w", err)
+	}
+	return nil
+}
+
+// checkstyle形式の出力を表す構造体
+type checkstyleResult struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// writeFindingsCheckstyle はcheckstyle形式のXMLで書き出す（ファイルは最初に現れた順）
+func writeFindingsCheckstyle(w io.Writer, findings []Finding) error {
+	result := checkstyleResult{Version: "4.3"}
+	fileIndexes := make(map[string]int)
+
+	for _, f := range findings {
+		index, ok := fileIndexes[f.File]
+		if !ok {
+			index = len(result.Files)
+			fileIndexes[f.File] = index
+			result.Files = append(result.Files, checkstyleFile{Name: f.File})
+		}
+		result.Files[index].Errors = append(result.Files[index].Errors, checkstyleError{
+			Line:     f.Range.Start.Line,
+			Column:   f.Range.Start.Column,
+			Severity: "warning",
+			Message:  f.Message(),
+			Source:   "grh." + f.Rule.ID,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("failed to encode checkstyle XML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// reviewdogのDiagnostic形式（rdjson、rdjsonl）を表す構造体
+// https://github.com/reviewdog/reviewdog/tree/master/proto/rdf
+// 桁番号はUTF-8のバイト単位
+type rdjsonResult struct {
+	Source      rdjsonSource       `json:"source"`
+	Severity    string             `json:"severity"`
+	Diagnostics []rdjsonDiagnostic `json:"diagnostics"`
+}
+
+type rdjsonSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type rdjsonDiagnostic struct {
+	Message     string             `json:"message"`
+	Location    rdjsonLocation     `json:"location"`
+	Severity    string             `json:"severity"`
+	Source      rdjsonSource       `json:"source"`
+	Code        rdjsonCode         `json:"code"`
+	Suggestions []rdjsonSuggestion `json:"suggestions,omitempty"`
+}
+
+type rdjsonLocation struct {
+	Path  string      `json:"path"`
+	Range rdjsonRange `json:"range"`
+}
+
+type rdjsonRange struct {
+	Start rdjsonPosition `json:"start"`
+	End   rdjsonPosition `json:"end"`
+}
+
+type rdjsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type rdjsonCode struct {
+	Value string `json:"value"`
+}
+
+type rdjsonSuggestion struct {
+	Range rdjsonRange `json:"range"`
+	Text  string      `json:"text"`
+}
+
+// grhSource はreviewdogの出力で使うツールの情報
+var grhSource = rdjsonSource{Name: "grh", URL: "https://github.com/ymotongpoo/grh"}
+
+// newRDJSONDiagnostic は Finding からDiagnosticを作る（fix が true の場合は置換の提案を付ける）
+func newRDJSONDiagnostic(f Finding, fix bool) rdjsonDiagnostic {
+	r := rdjsonRange{
+		Start: rdjsonPosition{Line: f.Range.Start.Line, Column: f.Range.Start.ByteColumn},
+		End:   rdjsonPosition{Line: f.Range.End.Line, Column: f.Range.End.ByteColumn},
+	}
+	diagnostic := rdjsonDiagnostic{
+		Message:  f.Message(),
+		Location: rdjsonLocation{Path: f.File, Range: r},
+		Severity: "WARNING",
+		Source:   grhSource,
+		Code:     rdjsonCode{Value: f.Rule.ID},
+	}
+	if fix {
+		diagnostic.Suggestions = []rdjsonSuggestion{{Range: r, Text: f.Suggestion}}
+	}
+	return diagnostic
+}
+
+// writeFindingsRDJSON はreviewdogのrdjson形式で書き出す
+func writeFindingsRDJSON(w io.Writer, findings []Finding) error {
+	result := rdjsonResult{
+		Source:      grhSource,
+		Severity:    "WARNING",
+		Diagnostics: make([]rdjsonDiagnostic, 0, len(findings)),
+	}
+	fixable := fixableFindings(findings)
+	for i, f := range findings {
+		result.Diagnostics = append(result.Diagnostics, newRDJSONDiagnostic(f, fixable[i]))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("failed to encode rdjson: %w", err)
+	}
+	return nil
+}
+
+// writeFindingsRDJSONL はreviewdogのrdjsonl形式（1行に1つのDiagnostic）で書き出す
+func writeFindingsRDJSONL(w io.Writer, findings []Finding) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	fixable := fixableFindings(findings)
+	for i, f := range findings {
+		if err := encoder.Encode(newRDJSONDiagnostic(f, fixable[i])); err != nil {
+			return fmt.Errorf("failed to encode rdjsonl: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeFindingsGitHub はGitHub Actionsのワークフローコマンド（::warning）で書き出す
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+func writeFindingsGitHub(w io.Writer, findings []Finding) error {
+	for _, f := range findings {
+		_, err := fmt.Fprintf(w, "::warning file=%s,line=%d,endLine=%d,col=%d,endColumn=%d,title=%s::%s\n",
+			escapeGitHubProperty(f.File),
+			f.Range.Start.Line, f.Range.End.Line,
+			f.Range.Start.Column, f.Range.End.Column,
+			escapeGitHubProperty("grh "+f.Rule.ID),
+			escapeGitHubData(f.Message()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeGitHubData はワークフローコマンドのメッセージ部分をエスケープする
+func escapeGitHubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	s = strings.ReplaceAll(s, "\n", "%0A")
+	return s
+}
+
+// escapeGitHubProperty はワークフローコマンドのプロパティ部分をエスケープする
+func escapeGitHubProperty(s string) string {
+	s = escapeGitHubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	s = strings.ReplaceAll(s, ",", "%2C")
+	return s
+}
@@ -0,0 +1,382 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// newTestFindings はテスト用のルールファイルとドキュメントから Finding の一覧を作る
+func newTestFindings(t *testing.T) []Finding {
+	t.Helper()
+
+	config, err := LoadConfig("testdata/yaml/simple.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	replacer := NewReplacerWithLogger(config, logger)
+
+	result := replacer.ReplaceString("jqueryとcookie\nハードウエア")
+	return NewFindings("doc.md", result)
+}
+
+func TestNewFindings(t *testing.T) {
+	findings := newTestFindings(t)
+
+	if len(findings) != 3 {
+		t.Fatalf("len(findings) = %d, want 3", len(findings))
+	}
+
+	// ルールの順序ではなく文書内の出現順に並ぶ
+	// Matched はマッチしたテキスト全体で、範囲と修正案はそのうち置換で変わる部分だけを指す
+	wants := []struct {
+		matched    string
+		suggestion string
+		ruleIndex  int
+		ruleLine   int
+		start      FindingPosition
+		end        FindingPosition
+	}{
+		{matched: "jquery", suggestion: "Q", ruleIndex: 1, ruleLine: 9, start: FindingPosition{Offset: 1, Line: 1, Column: 2, ByteColumn: 2}, end: FindingPosition{Offset: 2, Line: 1, Column: 3, ByteColumn: 3}},
+		{matched: "cookie", suggestion: "C", ruleIndex: 0, ruleLine: 4, start: FindingPosition{Offset: 9, Line: 1, Column: 8, ByteColumn: 10}, end: FindingPosition{Offset: 10, Line: 1, Column: 9, ByteColumn: 11}},
+		{matched: "ハードウエア", suggestion: "ェ", ruleIndex: 2, ruleLine: 17, start: FindingPosition{Offset: 28, Line: 2, Column: 5, ByteColumn: 13}, end: FindingPosition{Offset: 31, Line: 2, Column: 6, ByteColumn: 16}},
+	}
+
+	for i, want := range wants {
+		f := findings[i]
+		if f.Matched != want.matched || f.Suggestion != want.suggestion || f.Rule.Index != want.ruleIndex {
+			t.Errorf("findings[%d] = {%q, %q, rule %d}, want {%q, %q, rule %d}", i, f.Matched, f.Suggestion, f.Rule.Index, want.matched, want.suggestion, want.ruleIndex)
+		}
+		if f.Range.Start != want.start || f.Range.End != want.end {
+			t.Errorf("findings[%d].Range = %+v, want {%+v %+v}", i, f.Range, want.start, want.end)
+		}
+		if f.Rule.Source != "testdata/yaml/simple.yml" {
+			t.Errorf("findings[%d].Rule.Source = %q, want testdata/yaml/simple.yml", i, f.Rule.Source)
+		}
+		// ルールファイル内でルールが定義されていた位置
+		if f.Rule.Line != want.ruleLine || f.Rule.Column != 5 {
+			t.Errorf("findings[%d].Rule position = %d:%d, want %d:5", i, f.Rule.Line, f.Rule.Column, want.ruleLine)
+		}
+	}
+}
+
+func TestNewFindings_Duplicates(t *testing.T) {
+	// 同じ範囲に同じ修正案を示す変更は1件にまとめる
+	change := Change{From: "c", To: "C", Position: 0, Length: 1, Line: 1, Column: 1, Matched: "cookie", Replacement: "Cookie"}
+	result := &ReplaceResult{Original: "cookie", Result: "Cookie", Changed: true, Changes: []Change{change, change}}
+	if findings := NewFindings("doc.md", result); len(findings) != 1 {
+		t.Errorf("findings = %+v, want 1 finding", findings)
+	}
+}
+
+func TestWriteFindings_JSONL(t *testing.T) {
+	findings := newTestFindings(t)
+
+	var buf bytes.Buffer
+	if err := WriteFindings(&buf, "jsonl", findings); err != nil {
+		t.Fatalf("WriteFindings() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(findings) {
+		t.Fatalf("len(lines) = %d, want %d", len(lines), len(findings))
+	}
+
+	var decoded Finding
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+		t.Fatalf("Failed to decode JSON line: %v", err)
+	}
+	if decoded.Matched != "jquery" || decoded.Replacement != "jQuery" || decoded.Suggestion != "Q" || decoded.Rule.Expected != "jQuery" {
+		t.Errorf("decoded = %+v", decoded)
+	}
+}
+
+func TestWriteFindings_SARIF(t *testing.T) {
+	findings := newTestFindings(t)
+
+	var buf bytes.Buffer
+	if err := WriteFindings(&buf, "sarif", findings); err != nil {
+		t.Fatalf("WriteFindings() error = %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("Failed to decode SARIF: %v", err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF log: version %q, %d runs", log.Version, len(log.Runs))
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 3 || len(run.Results) != 3 {
+		t.Fatalf("rules = %d, results = %d, want 3 and 3", len(run.Tool.Driver.Rules), len(run.Results))
+	}
+
+	result := run.Results[1]
+	if run.Tool.Driver.Rules[result.RuleIndex].ID != result.RuleID {
+		t.Errorf("ruleIndex %d does not point to rule %q", result.RuleIndex, result.RuleID)
+	}
+	region := result.Locations[0].PhysicalLocation.Region
+	if region.StartLine != 1 || region.StartColumn != 8 || region.EndColumn != 9 {
+		t.Errorf("region = %+v", region)
+	}
+	if result.Fixes[0].ArtifactChanges[0].Replacements[0].InsertedContent.Text != "C" {
+		t.Errorf("fix = %+v", result.Fixes[0])
+	}
+}
+
+func TestWriteFindings_Checkstyle(t *testing.T) {
+	findings := newTestFindings(t)
+
+	var buf bytes.Buffer
+	if err := WriteFindings(&buf, "checkstyle", findings); err != nil {
+		t.Fatalf("WriteFindings() error = %v", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), "<?xml") {
+		t.Error("Checkstyle output should start with XML header")
+	}
+
+	var result checkstyleResult
+	if err := xml.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode checkstyle XML: %v", err)
+	}
+
+	if len(result.Files) != 1 || result.Files[0].Name != "doc.md" {
+		t.Fatalf("files = %+v", result.Files)
+	}
+	errs := result.Files[0].Errors
+	if len(errs) != 3 {
+		t.Fatalf("len(errors) = %d, want 3", len(errs))
+	}
+	if errs[2].Line != 2 || errs[2].Column != 5 || errs[2].Message != `"ハードウエア" -> "ハードウェア"` {
+		t.Errorf("errors[2] = %+v", errs[2])
+	}
+}
+
+func TestWriteFindings_UnknownFormat(t *testing.T) {
+	if err := WriteFindings(&bytes.Buffer{}, "unknown", nil); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestWriteFindings_RDJSONL(t *testing.T) {
+	findings := newTestFindings(t)
+
+	var buf bytes.Buffer
+	if err := WriteFindings(&buf, "rdjsonl", findings); err != nil {
+		t.Fatalf("WriteFindings() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(findings) {
+		t.Fatalf("len(lines) = %d, want %d", len(lines), len(findings))
+	}
+
+	var diagnostic rdjsonDiagnostic
+	if err := json.Unmarshal([]byte(lines[1]), &diagnostic); err != nil {
+		t.Fatalf("Failed to decode rdjsonl line: %v", err)
+	}
+
+	// reviewdogの桁番号はUTF-8のバイト単位
+	want := rdjsonRange{
+		Start: rdjsonPosition{Line: 1, Column: 10},
+		End:   rdjsonPosition{Line: 1, Column: 11},
+	}
+	if diagnostic.Location.Path != "doc.md" || diagnostic.Location.Range != want {
+		t.Errorf("location = %+v, want doc.md %+v", diagnostic.Location, want)
+	}
+	if len(diagnostic.Suggestions) != 1 || diagnostic.Suggestions[0].Text != "C" || diagnostic.Suggestions[0].Range != want {
+		t.Errorf("suggestions = %+v", diagnostic.Suggestions)
+	}
+}
+
+func TestWriteFindings_RDJSON(t *testing.T) {
+	findings := newTestFindings(t)
+
+	var buf bytes.Buffer
+	if err := WriteFindings(&buf, "rdjson", findings); err != nil {
+		t.Fatalf("WriteFindings() error = %v", err)
+	}
+
+	var result rdjsonResult
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode rdjson: %v", err)
+	}
+	if result.Source.Name != "grh" || len(result.Diagnostics) != len(findings) {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestWriteFindings_GitHub(t *testing.T) {
+	findings := newTestFindings(t)
+
+	var buf bytes.Buffer
+	if err := WriteFindings(&buf, "github", findings); err != nil {
+		t.Fatalf("WriteFindings() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	id := (&Rule{Expected: "Cookie"}).DerivedID()
+	want := `::warning file=doc.md,line=1,endLine=1,col=8,endColumn=9,title=grh ` + id + `::"cookie" -> "Cookie"`
+	if len(lines) != 3 || lines[1] != want {
+		t.Errorf("lines[1] = %q, want %q", lines[1], want)
+	}
+}
+
+func TestWriteFindings_FixesReproduceResult(t *testing.T) {
+	// 前のルールの置換結果をさらに置換する箇所を含む文書でも、修正案をすべて適用すると ReplaceString の結果と同じになる
+	config := &Config{Rules: []Rule{
+		{Expected: "サーバー$1", Pattern: "サーバ([^ー]|$)"},
+		{Expected: "Webサーバー", Pattern: "ウェブサーバー"},
+		{Expected: "ｂｂ", Pattern: "B"},
+		{Expected: "c", Pattern: "a?ｂ"},
+	}}
+	for i := range config.Rules {
+		if err := config.Rules[i].CompilePattern(); err != nil {
+			t.Fatalf("Failed to compile rule %d: %v", i, err)
+		}
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	input := "サーバとウェブサーバ\naB とウェブサーバーのサーバ\n"
+	result := NewReplacerWithLogger(config, logger).ReplaceString(input)
+	findings := NewFindings("doc.md", result)
+
+	// rdjsonl の修正案（桁番号はUTF-8のバイト単位）
+	var buf bytes.Buffer
+	if err := WriteFindings(&buf, "rdjsonl", findings); err != nil {
+		t.Fatalf("WriteFindings() error = %v", err)
+	}
+	var edits []testEdit
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var diagnostic rdjsonDiagnostic
+		if err := json.Unmarshal([]byte(line), &diagnostic); err != nil {
+			t.Fatalf("Failed to decode rdjsonl line: %v", err)
+		}
+		for _, s := range diagnostic.Suggestions {
+			edits = append(edits, testEdit{
+				start: byteOffset(input, s.Range.Start.Line, s.Range.Start.Column),
+				end:   byteOffset(input, s.Range.End.Line, s.Range.End.Column),
+				text:  s.Text,
+			})
+		}
+	}
+	if got := applyTestEdits(t, input, edits); got != result.Result {
+		t.Errorf("rdjsonl suggestions give %q, want %q", got, result.Result)
+	}
+
+	// SARIF の修正案（桁番号は文字単位）
+	buf.Reset()
+	if err := WriteFindings(&buf, "sarif", findings); err != nil {
+		t.Fatalf("WriteFindings() error = %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("Failed to decode SARIF: %v", err)
+	}
+	edits = nil
+	for _, r := range log.Runs[0].Results {
+		for _, fix := range r.Fixes {
+			for _, replacement := range fix.ArtifactChanges[0].Replacements {
+				region := replacement.DeletedRegion
+				edits = append(edits, testEdit{
+					start: runeOffset(input, region.StartLine, region.StartColumn),
+					end:   runeOffset(input, region.EndLine, region.EndColumn),
+					text:  replacement.InsertedContent.Text,
+				})
+			}
+		}
+	}
+	if got := applyTestEdits(t, input, edits); got != result.Result {
+		t.Errorf("SARIF fixes give %q, want %q", got, result.Result)
+	}
+}
+
+func TestFixableFindings(t *testing.T) {
+	// 同じファイルで前の Finding の範囲と重なるものには修正案を付けない
+	finding := func(file string, start, end int) Finding {
+		return Finding{File: file, Range: FindingRange{Start: FindingPosition{Offset: start}, End: FindingPosition{Offset: end}}}
+	}
+	findings := []Finding{finding("a.md", 0, 4), finding("a.md", 2, 3), finding("b.md", 2, 3), finding("a.md", 4, 5)}
+	want := []bool{true, false, true, true}
+	if got := fixableFindings(findings); !reflect.DeepEqual(got, want) {
+		t.Errorf("fixableFindings() = %v, want %v", got, want)
+	}
+}
+
+// testEdit は修正案を適用するテスト用の書き換え（start、end はバイトオフセット）
+type testEdit struct {
+	start, end int
+	text       string
+}
+
+// applyTestEdits は重ならない書き換えを後ろから順に適用する
+func applyTestEdits(t *testing.T, text string, edits []testEdit) string {
+	t.Helper()
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	for i, e := range edits {
+		if i > 0 && e.end > edits[i-1].start {
+			t.Fatalf("edits overlap: %+v and %+v", e, edits[i-1])
+		}
+		text = text[:e.start] + e.text + text[e.end:]
+	}
+	return text
+}
+
+// byteOffset は行番号と桁番号（いずれも1始まり、桁はバイト単位）に対応するバイトオフセットを返す
+func byteOffset(text string, line, column int) int {
+	offset := 0
+	for i := 1; i < line; i++ {
+		offset += strings.IndexByte(text[offset:], '\n') + 1
+	}
+	return offset + column - 1
+}
+
+// runeOffset は行番号と桁番号（いずれも1始まり、桁は文字単位）に対応するバイトオフセットを返す
+func runeOffset(text string, line, column int) int {
+	offset := byteOffset(text, line, 1)
+	for i := 1; i < column; i++ {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
+
+func TestEscapeGitHubProperty(t *testing.T) {
+	got := escapeGitHubProperty("a,b:c%d\ne")
+	want := "a%2Cb%3Ac%25d%0Ae"
+	if got != want {
+		t.Errorf("escapeGitHubProperty() = %q, want %q", got, want)
+	}
+}
@@ -15,49 +15,316 @@
 package grh
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Config はルールファイル全体の設定を表す構造体
 type Config struct {
-	Version     int       `yaml:"version" json:"version"`
-	Imports     []Import  `yaml:"imports,omitempty" json:"imports,omitempty"`
-	Rules       []Rule    `yaml:"rules" json:"rules"`
-	SourcePaths []string  `yaml:"sourcePaths,omitempty" json:"sourcePaths,omitempty"` // --rules-yaml, --rules-json用
+	Version           int      `yaml:"version" json:"version"`
+	Imports           []Import `yaml:"imports,omitempty" json:"imports,omitempty"`
+	Rules             []Rule   `yaml:"rules" json:"rules"`
+	SourcePaths       []string `yaml:"sourcePaths,omitempty" json:"sourcePaths,omitempty"`             // --rules-yaml, --rules-json用
+	SpecFailures      string   `yaml:"specFailures,omitempty" json:"specFailures,omitempty"`           // テストケースが失敗した場合の扱い（error または warn、デフォルトは error）
+	Specs             []Spec   `yaml:"specs,omitempty" json:"specs,omitempty"`                         // すべてのルールを適用する文書全体のテストケース（grh test で実行する）
+	Markdown          string   `yaml:"markdown,omitempty" json:"markdown,omitempty"`                   // 置換の対象から外す範囲を求めるMarkdownの解析方法（regexp または goldmark、デフォルトは regexp）
+	FrontMatterFields []string `yaml:"frontMatterFields,omitempty" json:"frontMatterFields,omitempty"` // 置換の対象にするフロントマターの項目（title など。それ以外のフロントマターは置換しない）
+
+	// 内部処理用（YAMLには出力されない）
+	specWarnings   []error      `yaml:"-" json:"-"` // specFailures: warn の場合に失敗したテストケース
+	specSuites     []*specSuite `yaml:"-" json:"-"` // インポートしたファイルも含めた文書全体のテストケース
+	ignoreWarnings []error      `yaml:"-" json:"-"` // どのルールにも該当しなかった ignoreRules の条件
+}
+
+// specSuite はルールファイルに書かれた文書全体のテストケースと、それを評価するルールの組
+type specSuite struct {
+	specs    []Spec
+	rules    []Rule          // テストケースを書いたファイルで（インポートも含めて）読み込んだルール
+	settings replaceSettings // テストケースを書いたファイルで（インポートも含めて）指定した置換の設定
+}
+
+// replaceSettings は文書を置換する際の、ルール以外の設定
+// テストケースを実際の置換と同じ設定で評価するために、ルールやテストケースとともに保持する
+type replaceSettings struct {
+	markdown          string
+	frontMatterFields []string
+}
+
+// replaceSettings は Config のルール以外の置換の設定を返す
+func (c *Config) replaceSettings() replaceSettings {
+	return replaceSettings{markdown: c.Markdown, frontMatterFields: c.FrontMatterFields}
+}
+
+// config は rules をこの設定で置換する Config を作る
+func (s replaceSettings) config(rules []Rule) *Config {
+	return &Config{Rules: rules, Markdown: s.markdown, FrontMatterFields: s.frontMatterFields}
+}
+
+// テストケースが失敗した場合の扱い
+const (
+	SpecFailuresError = "error" // ルールファイルの読み込みをエラーにする
+	SpecFailuresWarn  = "warn"  // 読み込みは続け、SpecWarnings で失敗を返す
+)
+
+// SpecWarnings は specFailures: warn のルールファイルで失敗したテストケースのエラーを返す
+func (c *Config) SpecWarnings() []error {
+	return c.specWarnings
+}
+
+// IgnoreWarnings はインポートしたルールのどれにも該当しなかった ignoreRules の条件を返す
+// IDの誤りや、パターンを変えて導出IDが変わったルールを指定している可能性がある
+func (c *Config) IgnoreWarnings() []error {
+	return c.ignoreWarnings
 }
 
 // Import は他の設定ファイルのインポート設定を表す構造体
 type Import struct {
-	Path           string   `yaml:"path,omitempty" json:"path,omitempty"`
-	DisableImports bool     `yaml:"disableImports,omitempty" json:"disableImports,omitempty"`
-	IgnoreRules    []string `yaml:"ignoreRules,omitempty" json:"ignoreRules,omitempty"`
+	Path           string       `yaml:"path,omitempty" json:"path,omitempty"`
+	DisableImports bool         `yaml:"disableImports,omitempty" json:"disableImports,omitempty"`
+	IgnoreRules    []IgnoreRule `yaml:"ignoreRules,omitempty" json:"ignoreRules,omitempty"`
 }
 
 // Rule は個別の置換ルールを表す構造体
 type Rule struct {
+	ID                  string   `yaml:"id,omitempty" json:"id,omitempty"` // 省略した場合はルールの内容から導出する
 	Expected            string   `yaml:"expected" json:"expected"`
 	Pattern             string   `yaml:"pattern,omitempty" json:"pattern,omitempty"`
 	Patterns            []string `yaml:"patterns,omitempty" json:"patterns,omitempty"`
 	RegexpMustEmpty     string   `yaml:"regexpMustEmpty,omitempty" json:"regexpMustEmpty,omitempty"`
 	Specs               []Spec   `yaml:"specs,omitempty" json:"specs,omitempty"`
 	IgnorePatternBefore string   `yaml:"ignorePatternBefore,omitempty" json:"ignorePatternBefore,omitempty"`
+	In                  []string `yaml:"in,omitempty" json:"in,omitempty"`             // 指定したMarkdownの要素の中だけに適用する（heading、linkText など）
+	NotIn               []string `yaml:"notIn,omitempty" json:"notIn,omitempty"`       // 指定したMarkdownの要素の中には適用しない
+	Disabled            bool     `yaml:"disabled,omitempty" json:"disabled,omitempty"` // デフォルトでは適用しない（文書のフロントマターの grh.enable で有効にする）
+	Priority            int      `yaml:"priority,omitempty" json:"priority,omitempty"` // 大きいほど先に適用する（デフォルトは0）
 
 	// 内部処理用（YAMLには出力されない）
-	compiledRegexp       *regexp.Regexp `yaml:"-" json:"-"`
-	compiledIgnoreBefore *regexp.Regexp `yaml:"-" json:"-"`
+	compiledRegexp       *regexp.Regexp  `yaml:"-" json:"-"`
+	compiledIgnoreBefore *regexp.Regexp  `yaml:"-" json:"-"`
+	mustEmptyGroup       int             `yaml:"-" json:"-"` // regexpMustEmptyで指定されたキャプチャグループの番号（未指定時は0）
+	source               SourcePosition  `yaml:"-" json:"-"` // ルールが定義されていたルールファイル内の位置
+	settings             replaceSettings `yaml:"-" json:"-"` // ルールが定義されていたルールファイルの置換の設定（document: true のテストケースに使う）
+}
+
+// SourcePosition はルールファイル内の位置を表す構造体
+type SourcePosition struct {
+	Path   string
+	Line   int // 行番号（1始まり、不明な場合は0）
+	Column int // 桁番号（1始まり、不明な場合は0）
+}
+
+// String は位置を「パス:行:桁」の形式で返す（不明な部分は省略する）
+func (p SourcePosition) String() string {
+	switch {
+	case p.Line == 0:
+		return p.Path
+	case p.Path == "":
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	default:
+		return fmt.Sprintf("%s:%d:%d", p.Path, p.Line, p.Column)
+	}
+}
+
+// errorf は位置が分かっている場合は先頭に位置を付けたエラーを作る
+func (p SourcePosition) errorf(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	if pos := p.String(); pos != "" {
+		return fmt.Errorf("%s: %w", pos, err)
+	}
+	return err
+}
+
+// SourcePath はルールが定義されていたルールファイルのパスを返す
+func (r *Rule) SourcePath() string {
+	return r.source.Path
+}
+
+// Source はルールが定義されていたルールファイル内の位置を返す
+func (r *Rule) Source() SourcePosition {
+	return r.source
+}
+
+// UnmarshalYAML はルールを読み込み、ルールファイル内の位置を記録する
+func (r *Rule) UnmarshalYAML(value *yaml.Node) error {
+	// UnmarshalYAML を再帰的に呼ばないよう別の型として読み込む
+	type plain Rule
+	if err := value.Decode((*plain)(r)); err != nil {
+		return err
+	}
+	r.source = SourcePosition{Line: value.Line, Column: value.Column}
+	return nil
+}
+
+// setSourcePath はルールとテストケースの位置にルールファイルのパスを設定する
+func (r *Rule) setSourcePath(path string) {
+	r.source.Path = path
+	for i := range r.Specs {
+		r.Specs[i].source.Path = path
+	}
+}
+
+// CompiledPattern はコンパイル済みの正規表現の文字列を返す（未コンパイルの場合は空文字列）
+func (r *Rule) CompiledPattern() string {
+	if r.compiledRegexp == nil {
+		return ""
+	}
+	return r.compiledRegexp.String()
+}
+
+// DerivedID はルールの内容から導出したIDを返す
+// マッチに関わる expected、pattern、patterns、regexpMustEmpty、ignorePatternBefore、in、notIn だけから求めるため、
+// ルールファイル内での位置や specs を変えても変わらない
+func (r *Rule) DerivedID() string {
+	h := sha256.New()
+	fields := []string{r.Expected, r.Pattern, strings.Join(r.Patterns, "\x00"), r.RegexpMustEmpty, r.IgnorePatternBefore}
+	// 適用する範囲を指定したルールだけ、範囲もIDに含める（指定していないルールのIDは変わらない）
+	if r.hasScope() {
+		fields = append(fields, strings.Join(r.In, ","), strings.Join(r.NotIn, ","))
+	}
+	for _, field := range fields {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return "rule-" + hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+// id はルールのIDを返す（CompilePattern を呼ぶ前で ID が空の場合は DerivedID を返す）
+func (r *Rule) id() string {
+	if r.ID == "" {
+		return r.DerivedID()
+	}
+	return r.ID
+}
+
+// validateID はルールファイルで指定されたIDを検証する
+// IDはコメントでカンマ区切りで指定するため、空白やカンマは使えない
+func (r *Rule) validateID() error {
+	if strings.ContainsFunc(r.ID, func(c rune) bool { return c == ',' || unicode.IsSpace(c) }) {
+		return fmt.Errorf("invalid rule id %q: must not contain spaces or commas", r.ID)
+	}
+	return nil
 }
 
 // Spec はルールのテストケースを表す構造体
+// keep を指定した場合（from だけを書いて to を省略した場合も同じ）は、ルールを適用してもテキストが変わらないことを検証する
 type Spec struct {
-	From string `yaml:"from" json:"from"`
-	To   string `yaml:"to" json:"to"`
+	From     string `yaml:"from" json:"from"`
+	To       string `yaml:"to" json:"to"`
+	Keep     string `yaml:"keep,omitempty" json:"keep,omitempty"`
+	Document bool   `yaml:"document,omitempty" json:"document,omitempty"` // Replacer 全体で評価する（コードやショートコードの保護、抑止コメントも適用される）
+
+	source SourcePosition // テストケースが定義されていたルールファイル内の位置
+}
+
+// Source はテストケースが定義されていたルールファイル内の位置を返す
+func (s Spec) Source() SourcePosition {
+	return s.source
+}
+
+// Input はルールを適用するテキストを返す
+func (s Spec) Input() string {
+	if s.Keep != "" {
+		return s.Keep
+	}
+	return s.From
+}
+
+// Want はルールを適用した結果として期待するテキストを返す
+func (s Spec) Want() string {
+	if s.Keep != "" {
+		return s.Keep
+	}
+	return s.To
+}
+
+// UnmarshalYAML はテストケースを読み込み、ルールファイル内の位置を記録する
+// to を省略した場合は keep として扱う
+func (s *Spec) UnmarshalYAML(value *yaml.Node) error {
+	type plain Spec
+	if err := value.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.source = SourcePosition{Line: value.Line, Column: value.Column}
+
+	keys := make(map[string]bool)
+	if value.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			keys[value.Content[i].Value] = true
+		}
+	}
+	if keys["keep"] && (keys["from"] || keys["to"]) {
+		return fmt.Errorf("line %d: spec must not have both keep and from/to", value.Line)
+	}
+	if keys["from"] && !keys["to"] {
+		s.Keep, s.From = s.From, ""
+	}
+	return nil
+}
+
+// MarshalYAML は keep のテストケースを keep だけの形式で書き出す
+func (s Spec) MarshalYAML() (interface{}, error) {
+	return s.marshalValue(), nil
+}
+
+// MarshalJSON は keep のテストケースを keep だけの形式で書き出す
+func (s Spec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.marshalValue())
+}
+
+func (s Spec) marshalValue() interface{} {
+	if s.Keep != "" {
+		return struct {
+			Keep     string `yaml:"keep" json:"keep"`
+			Document bool   `yaml:"document,omitempty" json:"document,omitempty"`
+		}{s.Keep, s.Document}
+	}
+	type plain Spec
+	return plain(s)
+}
+
+// MarshalYAML は --rules-yaml 用に、コンパイル済みのパターンも compiledPattern として書き出す
+// ignoreRules の pattern はこの値と比較するため、除外するルールを指定する際に確認できるようにする
+func (r Rule) MarshalYAML() (interface{}, error) {
+	return r.marshalValue(), nil
+}
+
+// MarshalJSON は --rules-json 用に、コンパイル済みのパターンも compiledPattern として書き出す
+func (r Rule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.marshalValue())
+}
+
+func (r Rule) marshalValue() interface{} {
+	type plain Rule
+	return struct {
+		plain           `yaml:",inline"`
+		CompiledPattern string `yaml:"compiledPattern,omitempty" json:"compiledPattern,omitempty"`
+	}{plain(r), r.CompiledPattern()}
 }
 
 // CompilePattern はルールのパターンを正規表現にコンパイルする
+// ID が空の場合は DerivedID で導出したIDを設定する
 func (r *Rule) CompilePattern() error {
+	if err := r.validateID(); err != nil {
+		return err
+	}
+	if err := validateScopes(r.In, r.NotIn); err != nil {
+		return err
+	}
+	if r.ID == "" {
+		r.ID = r.DerivedID()
+	}
+
 	var pattern string
 	
 	if r.Pattern != "" {
@@ -99,9 +366,44 @@ func (r *Rule) CompilePattern() error {
 		}
 		r.compiledIgnoreBefore = compiledIgnore
 	}
+
+	r.mustEmptyGroup = 0
+	if r.RegexpMustEmpty != "" {
+		group, err := r.resolveMustEmptyGroup()
+		if err != nil {
+			return err
+		}
+		r.mustEmptyGroup = group
+	}
 	return nil
 }
 
+// resolveMustEmptyGroup は regexpMustEmpty の値からキャプチャグループの番号を求める
+// $1、1、${name}、$name、name のいずれの形式も受け付ける
+func (r *Rule) resolveMustEmptyGroup() (int, error) {
+	ref := strings.TrimSpace(r.RegexpMustEmpty)
+	ref = strings.TrimPrefix(ref, "$")
+	if strings.HasPrefix(ref, "{") && strings.HasSuffix(ref, "}") {
+		ref = ref[1 : len(ref)-1]
+	}
+	if ref == "" {
+		return 0, fmt.Errorf("invalid regexpMustEmpty %q", r.RegexpMustEmpty)
+	}
+
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n <= 0 || n > r.compiledRegexp.NumSubexp() {
+			return 0, fmt.Errorf("regexpMustEmpty %q refers to nonexistent capture group", r.RegexpMustEmpty)
+		}
+		return n, nil
+	}
+
+	n := r.compiledRegexp.SubexpIndex(ref)
+	if n < 0 {
+		return 0, fmt.Errorf("regexpMustEmpty %q refers to nonexistent capture group", r.RegexpMustEmpty)
+	}
+	return n, nil
+}
+
 // generateCaseInsensitivePattern は expected 値から大文字小文字全角半角統一パターンを生成
 func (r *Rule) generateCaseInsensitivePattern() string {
 	expected := r.Expected
@@ -173,75 +475,277 @@ func (r *Rule) toHalfwidthAlphabet(s string) string {
 
 // Replace はio.Readerから読み込んだテキストに対してルールを適用して置換を行う
 func (r *Rule) Replace(reader io.Reader) (string, error) {
-	if r.compiledRegexp == nil {
-		// パターンがコンパイルされていない場合は、そのまま読み込んで返す
-		content, err := io.ReadAll(reader)
-		if err != nil {
-			return "", fmt.Errorf("failed to read from reader: %w", err)
-		}
-		return string(content), nil
-	}
-	
 	content, err := io.ReadAll(reader)
 	if err != nil {
 		return "", fmt.Errorf("failed to read from reader: %w", err)
 	}
-	
-	return r.compiledRegexp.ReplaceAllString(string(content), r.Expected), nil
+
+	return r.ReplaceString(string(content)), nil
 }
 
 // ReplaceString はテキストに対してルールを適用して置換を行う
 func (r *Rule) ReplaceString(text string) string {
+	result, _ := r.replaceAll(text)
+	return result
+}
+
+// ruleMatch はルールによる1箇所分の置換を表す（位置は置換前のテキスト上のバイトオフセット）
+// マッチした範囲のうち、置換によって実際に変わる部分だけを表す
+type ruleMatch struct {
+	Start int
+	End   int
+	From  string
+	To    string
+	// マッチした範囲全体（置換の前後で変わらない部分やパターンの前後の文脈も含む）
+	MatchStart int
+	MatchEnd   int
+}
+
+// replaceAll はテキストに対してルールを適用し、置換結果と実際に置換した箇所の一覧を返す
+func (r *Rule) replaceAll(text string) (string, []ruleMatch) {
+	return r.replaceAllFunc(text, nil)
+}
+
+// replaceAllFunc は replaceAll と同じだが、skip が true を返した範囲（text 上の開始位置と終了位置）は置換しない
+func (r *Rule) replaceAllFunc(text string, skip func(start, end int) bool) (string, []ruleMatch) {
+	return r.replaceInSegments(text, []TextSpan{{Start: 0, End: len(text)}}, skip)
+}
+
+// replaceInSegments は text のうち segments（開始位置の順に並んだ重ならない範囲）の中だけにルールを適用する
+// 正規表現はテキスト全体に対して実行するため、^ や $ は保護された範囲の前後ではなく行頭と行末にだけマッチする。
+// segments の外を含むマッチは置換しない。ただし segments の外の部分を置換後もそのまま残す前後の文脈
+// （サーバ([^ー]|$) の ([^ー]|$) など）は、範囲の外を除いた部分の置換として扱う。
+// ignorePatternBefore は範囲の外も含めた直前の文脈で判定する。
+func (r *Rule) replaceInSegments(text string, segments []TextSpan, skip func(start, end int) bool) (string, []ruleMatch) {
 	if r.compiledRegexp == nil {
-		return text
+		return text, nil
 	}
-	
-	// ignorePatternBeforeが設定されている場合の処理
-	if r.compiledIgnoreBefore != nil {
-		var sb strings.Builder
-		lastIndex := 0
-		matches := r.compiledRegexp.FindAllStringIndex(text, -1)
 
-		for _, matchIndices := range matches {
-			startIndex := matchIndices[0]
-			endIndex := matchIndices[1]
+	outside := complementSpans(mergeSpans(segments), len(text))
 
-			sb.WriteString(text[lastIndex:startIndex])
+	var sb strings.Builder
+	var applied []ruleMatch
+	lastIndex := 0
 
-			contextBefore := text[:startIndex]
+	for _, match := range r.compiledRegexp.FindAllStringSubmatchIndex(text, -1) {
+		startIndex := match[0]
+		endIndex := match[1]
 
-			shouldIgnore := r.compiledIgnoreBefore.MatchString(contextBefore)
+		sb.WriteString(text[lastIndex:startIndex])
+		lastIndex = endIndex
 
-			if shouldIgnore {
-				sb.WriteString(text[startIndex:endIndex])
-			} else {
-				sb.WriteString(r.Expected)
-			}
-			lastIndex = endIndex
+		if r.shouldSkip(text, match) {
+			sb.WriteString(text[startIndex:endIndex])
+			continue
+		}
+		replacement := string(r.compiledRegexp.ExpandString(nil, r.Expected, text, match))
+		m, ok := trimOutside(text, startIndex, endIndex, replacement, outside)
+		if ok {
+			m = trimUnchanged(m)
+			m.MatchStart, m.MatchEnd = startIndex, endIndex
+		}
+		if !ok || skip != nil && skip(m.Start, m.End) {
+			sb.WriteString(text[startIndex:endIndex])
+			continue
+		}
+		sb.WriteString(replacement)
+		// 置換前後で変化がない箇所は置換として扱わない
+		if m.To != m.From {
+			applied = append(applied, m)
 		}
-		sb.WriteString(text[lastIndex:])
-		result := sb.String()
-		
-		return result
 	}
-	
-	return r.compiledRegexp.ReplaceAllString(text, r.Expected)
+	sb.WriteString(text[lastIndex:])
+
+	return sb.String(), applied
 }
 
-// ValidateSpecs はルールのテストケースを検証する
-func (r *Rule) ValidateSpecs() error {
+// trimOutside は text の start から end までのマッチを replacement に置換する場合の置換箇所を返す
+// outside（mergeSpans 済み）と重なる部分は、マッチの先頭か末尾にあり、replacement にそのまま残る場合に限り
+// 置換箇所から除く。それ以外で outside と重なる場合は false を返す。
+func trimOutside(text string, start, end int, replacement string, outside []TextSpan) (ruleMatch, bool) {
+	s, e := start, end
+	for _, span := range outside {
+		if span.End <= start || span.Start >= end {
+			continue
+		}
+		switch {
+		case start == end:
+			// 範囲の外の途中にある空のマッチ
+			return ruleMatch{}, false
+		case span.Start <= start:
+			// 範囲の外から始まるマッチ
+			s = min(span.End, end)
+		case span.End >= end:
+			// 範囲の外で終わるマッチ
+			e = max(span.Start, s)
+		default:
+			// マッチの途中に範囲の外がある
+			return ruleMatch{}, false
+		}
+	}
+	if s == start && e == end {
+		return ruleMatch{Start: start, End: end, From: text[start:end], To: replacement}, true
+	}
+
+	prefix, suffix := text[start:s], text[e:end]
+	if s >= e || !strings.HasPrefix(replacement, prefix) || !strings.HasSuffix(replacement[len(prefix):], suffix) {
+		return ruleMatch{}, false
+	}
+	return ruleMatch{
+		Start: s,
+		End:   e,
+		From:  text[s:e],
+		To:    replacement[len(prefix) : len(replacement)-len(suffix)],
+	}, true
+}
+
+// trimUnchanged は置換箇所から、置換の前後で変わらない先頭と末尾の部分を除く
+// サーバ([^ー]|$) の ([^ー]|$) のような前後の文脈を除き、置換箇所の位置と長さが実際に変わるテキストだけを指すようにする。
+// 除く部分は文字の境界でそろえる。置換後のテキストを挿入するだけになる場合は、位置があいまいにならないよう
+// 直前（先頭の場合は直後）の1文字を残す
+func trimUnchanged(m ruleMatch) ruleMatch {
+	prefix := 0
+	for prefix < len(m.From) && prefix < len(m.To) {
+		_, size := utf8.DecodeRuneInString(m.From[prefix:])
+		if !strings.HasPrefix(m.To[prefix:], m.From[prefix:prefix+size]) {
+			break
+		}
+		prefix += size
+	}
+	suffix := 0
+	for suffix < len(m.From)-prefix && suffix < len(m.To)-prefix {
+		_, size := utf8.DecodeLastRuneInString(m.From[prefix : len(m.From)-suffix])
+		if !strings.HasSuffix(m.To[prefix:len(m.To)-suffix], m.From[len(m.From)-suffix-size:len(m.From)-suffix]) {
+			break
+		}
+		suffix += size
+	}
+	if prefix+suffix == len(m.From) && len(m.From) > 0 {
+		if prefix > 0 {
+			_, size := utf8.DecodeLastRuneInString(m.From[:prefix])
+			prefix -= size
+		} else {
+			_, size := utf8.DecodeRuneInString(m.From[len(m.From)-suffix:])
+			suffix -= size
+		}
+	}
+	return ruleMatch{
+		Start: m.Start + prefix,
+		End:   m.End - suffix,
+		From:  m.From[prefix : len(m.From)-suffix],
+		To:    m.To[prefix : len(m.To)-suffix],
+	}
+}
+
+// shouldSkip はマッチした箇所を置換せずに残すべきかどうかを判定する
+// match は FindAllStringSubmatchIndex が返す1件分のインデックス
+func (r *Rule) shouldSkip(text string, match []int) bool {
+	// ignorePatternBeforeが設定されている場合は直前の文脈をチェック
+	if r.compiledIgnoreBefore != nil && r.compiledIgnoreBefore.MatchString(text[:match[0]]) {
+		return true
+	}
+
+	// regexpMustEmptyが設定されている場合は指定のキャプチャグループが空であることを要求する
+	if r.mustEmptyGroup > 0 {
+		start, end := match[2*r.mustEmptyGroup], match[2*r.mustEmptyGroup+1]
+		if start >= 0 && end > start {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SpecResult はテストケース1件の実行結果を表す構造体
+type SpecResult struct {
+	Spec   Spec
+	Got    string // ルールを適用した結果
+	Passed bool
+}
+
+// Description は期待した結果と実際の結果を表示用の文字列にする
+// 複数行のテキストの場合は期待した結果と実際の結果の差分を返す
+func (sr SpecResult) Description() string {
+	if want := sr.Spec.Want(); strings.Contains(want, "\n") || strings.Contains(sr.Got, "\n") {
+		return "result differs from the expected text:\n" + unifiedDiff(want, sr.Got, "want", "got", 3)
+	}
+	if sr.Spec.Keep != "" {
+		return fmt.Sprintf("%q expected to be kept, but got %q", sr.Spec.Keep, sr.Got)
+	}
+	return fmt.Sprintf("%q expected %q, but got %q", sr.Spec.From, sr.Spec.To, sr.Got)
+}
+
+// Err はテストケースが失敗した場合にルールファイル内の位置を含むエラーを返す（成功した場合は nil）
+func (sr SpecResult) Err(r *Rule) error {
+	if sr.Passed {
+		return nil
+	}
+	return sr.Spec.source.errorf("spec failed for rule %s (%s): %s", r.id(), r.Expected, sr.Description())
+}
+
+// RunSpecs はルールのテストケースをすべて実行し、それぞれの結果を返す
+// document: true のテストケースは、このルールだけを持つ Replacer で評価する
+func (r *Rule) RunSpecs() ([]SpecResult, error) {
 	if r.compiledRegexp == nil {
 		if err := r.CompilePattern(); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	
+
+	var replacer *Replacer
+	results := make([]SpecResult, 0, len(r.Specs))
 	for _, spec := range r.Specs {
-		result := r.ReplaceString(spec.From)
-		if result != spec.To {
-			return fmt.Errorf("spec failed: %q expected %q, but got %q", spec.From, spec.To, result)
+		var got string
+		if spec.Document {
+			if replacer == nil {
+				// デフォルトでは適用しないルールもテストケースは評価する
+				rule := *r
+				rule.Disabled = false
+				replacer = NewReplacerWithLogger(r.settings.config([]Rule{rule}), discardLogger())
+			}
+			got = replacer.ReplaceString(spec.Input()).Result
+		} else {
+			got = r.ReplaceString(spec.Input())
 		}
+		results = append(results, SpecResult{Spec: spec, Got: got, Passed: got == spec.Want()})
 	}
-	
-	return nil
+	return results, nil
+}
+
+// ValidateSpecs はルールのテストケースを検証する
+// 失敗したテストケースが複数ある場合は、すべての失敗をまとめたエラーを返す
+func (r *Rule) ValidateSpecs() error {
+	results, err := r.RunSpecs()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, result := range results {
+		if err := result.Err(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunDocumentSpecs はルールファイルの specs に書いた文書全体のテストケースをすべて実行し、それぞれの結果を返す
+// テストケースはそれを書いたファイルで（インポートも含めて）読み込んだすべてのルールを適用する順に使い、
+// Replacer.ReplaceString で評価する
+func (c *Config) RunDocumentSpecs() []SpecResult {
+	suites := c.specSuites
+	if suites == nil && len(c.Specs) > 0 {
+		// ローダーを使わずに作った Config の場合はこの Config のルールで評価する
+		suites = []*specSuite{{specs: c.Specs, rules: c.Rules, settings: c.replaceSettings()}}
+	}
+
+	var results []SpecResult
+	for _, suite := range suites {
+		replacer := NewReplacerWithLogger(suite.settings.config(suite.rules), discardLogger())
+		for _, spec := range suite.specs {
+			got := replacer.ReplaceString(spec.Input()).Result
+			results = append(results, SpecResult{Spec: spec, Got: got, Passed: got == spec.Want()})
+		}
+	}
+	return results
 }
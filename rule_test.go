@@ -17,6 +17,8 @@ package grh
 import (
 	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestRule_CompilePattern(t *testing.T) {
@@ -26,33 +28,68 @@ func TestRule_CompilePattern(t *testing.T) {
 		wantErr bool
 	}{
 		{
-			name: "simple expected pattern",
-			rule: Rule{Expected: "Cookie"},
+			name:    "simple expected pattern",
+			rule:    Rule{Expected: "Cookie"},
+			wantErr: false,
+		},
+		{
+			name:    "explicit pattern",
+			rule:    Rule{Expected: "jQuery", Pattern: "[jJ][qQ][uU][eE][rR][yY]"},
+			wantErr: false,
+		},
+		{
+			name:    "multiple patterns",
+			rule:    Rule{Expected: "ハードウェア", Patterns: []string{"ハードウエアー", "ハードウェアー", "ハードウエア"}},
 			wantErr: false,
 		},
 		{
-			name: "explicit pattern",
-			rule: Rule{Expected: "jQuery", Pattern: "[jJ][qQ][uU][eE][rR][yY]"},
+			name:    "regex pattern with slashes",
+			rule:    Rule{Expected: "（$1）", Pattern: "/\\(([^)]+)\\)/"},
 			wantErr: false,
 		},
 		{
-			name: "multiple patterns",
-			rule: Rule{Expected: "ハードウェア", Patterns: []string{"ハードウエアー", "ハードウェアー", "ハードウエア"}},
+			name:    "fullwidth alphabet expected pattern",
+			rule:    Rule{Expected: "Hello"},
 			wantErr: false,
 		},
 		{
-			name: "regex pattern with slashes",
-			rule: Rule{Expected: "（$1）", Pattern: "/\\(([^)]+)\\)/"},
+			name:    "no pattern or expected",
+			rule:    Rule{},
+			wantErr: true,
+		},
+		{
+			name:    "regexpMustEmpty with numbered group",
+			rule:    Rule{Expected: "ソフトウェア", Pattern: "(日経)?ソフトウエア", RegexpMustEmpty: "$1"},
 			wantErr: false,
 		},
 		{
-			name: "fullwidth alphabet expected pattern",
-			rule: Rule{Expected: "Hello"},
+			name:    "regexpMustEmpty with named group",
+			rule:    Rule{Expected: "ソフトウェア", Pattern: "(?P<book>日経)?ソフトウエア", RegexpMustEmpty: "${book}"},
+			wantErr: false,
+		},
+		{
+			name:    "regexpMustEmpty with nonexistent group",
+			rule:    Rule{Expected: "ソフトウェア", Pattern: "(日経)?ソフトウエア", RegexpMustEmpty: "$2"},
+			wantErr: true,
+		},
+		{
+			name:    "regexpMustEmpty with unknown group name",
+			rule:    Rule{Expected: "ソフトウェア", Pattern: "(日経)?ソフトウエア", RegexpMustEmpty: "$book"},
+			wantErr: true,
+		},
+		{
+			name:    "explicit id",
+			rule:    Rule{ID: "cookie", Expected: "Cookie"},
 			wantErr: false,
 		},
 		{
-			name: "no pattern or expected",
-			rule: Rule{},
+			name:    "id with spaces",
+			rule:    Rule{ID: "my cookie", Expected: "Cookie"},
+			wantErr: true,
+		},
+		{
+			name:    "id with commas",
+			rule:    Rule{ID: "cookie,jquery", Expected: "Cookie"},
 			wantErr: true,
 		},
 	}
@@ -67,6 +104,38 @@ func TestRule_CompilePattern(t *testing.T) {
 	}
 }
 
+func TestRule_DerivedID(t *testing.T) {
+	paren := Rule{Expected: "（$1）", Pattern: `\(([^)]+)\)`}
+	bracket := Rule{Expected: "（$1）", Pattern: `（([^）]+)\)`}
+
+	// expected が同じでも pattern が異なれば別のIDになる
+	if paren.DerivedID() == bracket.DerivedID() {
+		t.Errorf("DerivedID() should differ for rules with different patterns: %q", paren.DerivedID())
+	}
+
+	// specs はIDに影響しない
+	withSpecs := paren
+	withSpecs.Specs = []Spec{{From: "(a)", To: "（a）"}}
+	if withSpecs.DerivedID() != paren.DerivedID() {
+		t.Errorf("DerivedID() = %q, want %q", withSpecs.DerivedID(), paren.DerivedID())
+	}
+
+	// CompilePattern は ID が空の場合だけ導出したIDを設定する
+	if err := paren.CompilePattern(); err != nil {
+		t.Fatalf("CompilePattern() error = %v", err)
+	}
+	if paren.ID != paren.DerivedID() {
+		t.Errorf("ID = %q, want %q", paren.ID, paren.DerivedID())
+	}
+	named := Rule{ID: "paren", Expected: "（$1）", Pattern: `\(([^)]+)\)`}
+	if err := named.CompilePattern(); err != nil {
+		t.Fatalf("CompilePattern() error = %v", err)
+	}
+	if named.ID != "paren" {
+		t.Errorf("ID = %q, want %q", named.ID, "paren")
+	}
+}
+
 func TestRule_Replace(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -184,6 +253,30 @@ func TestRule_ReplaceString(t *testing.T) {
 			input:    "ｈＥｌＬｏ world",
 			expected: "Hello world",
 		},
+		{
+			name:     "regexpMustEmpty - empty group is replaced",
+			rule:     Rule{Expected: "ソフトウェア", Pattern: "(日経)?ソフトウエア", RegexpMustEmpty: "$1"},
+			input:    "広義のソフトウエア",
+			expected: "広義のソフトウェア",
+		},
+		{
+			name:     "regexpMustEmpty - non-empty group is kept",
+			rule:     Rule{Expected: "ソフトウェア", Pattern: "(日経)?ソフトウエア", RegexpMustEmpty: "$1"},
+			input:    "日経ソフトウエアと広義のソフトウエア",
+			expected: "日経ソフトウエアと広義のソフトウェア",
+		},
+		{
+			name:     "regexpMustEmpty - named group",
+			rule:     Rule{Expected: "ソフトウェア", Pattern: "(?P<book>日経)?ソフトウエア", RegexpMustEmpty: "book"},
+			input:    "日経ソフトウエアと広義のソフトウエア",
+			expected: "日経ソフトウエアと広義のソフトウェア",
+		},
+		{
+			name:     "regexpMustEmpty - capture group expanded in expected",
+			rule:     Rule{Expected: "サーバー$2", Pattern: "(Web)?サーバ([^ー]|$)", RegexpMustEmpty: "$1"},
+			input:    "Webサーバの設定とサーバの設定",
+			expected: "Webサーバの設定とサーバーの設定",
+		},
 	}
 
 	for _, tt := range tests {
@@ -219,6 +312,19 @@ func TestRule_ValidateSpecs(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "regexpMustEmpty specs",
+			rule: Rule{
+				Expected:        "ソフトウェア",
+				Pattern:         "(日経)?ソフトウエア",
+				RegexpMustEmpty: "$1",
+				Specs: []Spec{
+					{From: "広義のソフトウエア", To: "広義のソフトウェア"},
+					{From: "日経ソフトウエア", To: "日経ソフトウエア"},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid specs",
 			rule: Rule{
@@ -230,6 +336,51 @@ func TestRule_ValidateSpecs(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "keep specs",
+			rule: Rule{
+				Expected:            "オペレーター",
+				Pattern:             "オペレータ(?:ー)?",
+				IgnorePatternBefore: "Kubernetes$",
+				Specs: []Spec{
+					{From: "オペレータ", To: "オペレーター"},
+					{Keep: "Kubernetesオペレータ"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "failing keep spec",
+			rule: Rule{
+				Expected: "オペレーター",
+				Pattern:  "オペレータ(?:ー)?",
+				Specs: []Spec{
+					{Keep: "Kubernetesオペレータ"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "document spec protects code spans",
+			rule: Rule{
+				Expected: "Cookie",
+				Specs: []Spec{
+					{From: "cookie `cookie`", To: "Cookie `cookie`", Document: true},
+					{Keep: "```\ncookie\n```\n", Document: true},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "non-document spec does not protect code spans",
+			rule: Rule{
+				Expected: "Cookie",
+				Specs: []Spec{
+					{From: "cookie `cookie`", To: "Cookie `cookie`"},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -241,3 +392,69 @@ func TestRule_ValidateSpecs(t *testing.T) {
 		})
 	}
 }
+
+func TestSpec_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    Spec
+		wantErr bool
+	}{
+		{
+			name: "from and to",
+			yaml: "from: jquery\nto: jQuery\n",
+			want: Spec{From: "jquery", To: "jQuery"},
+		},
+		{
+			name: "to with empty string",
+			yaml: "from: jquery\nto: \"\"\n",
+			want: Spec{From: "jquery"},
+		},
+		{
+			name: "keep",
+			yaml: "keep: Kubernetesオペレーター\n",
+			want: Spec{Keep: "Kubernetesオペレーター"},
+		},
+		{
+			name: "to omitted",
+			yaml: "from: Kubernetesオペレーター\ndocument: true\n",
+			want: Spec{Keep: "Kubernetesオペレーター", Document: true},
+		},
+		{
+			name:    "keep with from",
+			yaml:    "keep: a\nfrom: a\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var spec Spec
+			err := yaml.Unmarshal([]byte(tt.yaml), &spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			spec.source = SourcePosition{}
+			if spec != tt.want {
+				t.Errorf("Unmarshal() = %+v, want %+v", spec, tt.want)
+			}
+
+			// 書き出した結果を読み込むと同じテストケースになる
+			data, err := yaml.Marshal(spec)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var again Spec
+			if err := yaml.Unmarshal(data, &again); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			again.source = SourcePosition{}
+			if again != tt.want {
+				t.Errorf("Round trip = %+v, want %+v (yaml: %s)", again, tt.want, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ルールの in、notIn に指定できるMarkdownの要素
+const (
+	ScopeHeading    = "heading"    // 見出し
+	ScopeParagraph  = "paragraph"  // 段落
+	ScopeLinkText   = "linkText"   // リンクテキスト
+	ScopeImageAlt   = "imageAlt"   // 画像の代替テキスト
+	ScopeTableCell  = "tableCell"  // 表のセル
+	ScopeListItem   = "listItem"   // リストの項目
+	ScopeBlockquote = "blockquote" // 引用
+)
+
+// Scopes はルールの in、notIn に指定できるMarkdownの要素の一覧を返す
+func Scopes() []string {
+	return []string{ScopeHeading, ScopeParagraph, ScopeLinkText, ScopeImageAlt, ScopeTableCell, ScopeListItem, ScopeBlockquote}
+}
+
+// validateScopes は in、notIn に指定した要素がすべて対応しているものかを検証する
+func validateScopes(in, notIn []string) error {
+	for _, scopes := range [][]string{in, notIn} {
+		for _, scope := range scopes {
+			if !slices.Contains(Scopes(), scope) {
+				return fmt.Errorf("unknown scope %q: must be one of %s", scope, strings.Join(Scopes(), ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// hasScope はルールが in、notIn で適用する範囲を指定しているかを返す
+func (r *Rule) hasScope() bool {
+	return len(r.In) > 0 || len(r.NotIn) > 0
+}
+
+// scopeSegments は segments のうち、ルールの in、notIn に合う範囲だけを返す
+// scopes はMarkdownの要素ごとの範囲（mergeSpans 済み）
+func (r *Rule) scopeSegments(segments []TextSpan, scopes map[string][]TextSpan) []TextSpan {
+	if len(r.In) > 0 {
+		var allowed []TextSpan
+		for _, scope := range r.In {
+			allowed = append(allowed, scopes[scope]...)
+		}
+		segments = intersectSpans(segments, mergeSpans(allowed))
+	}
+	if len(r.NotIn) > 0 {
+		var denied []TextSpan
+		for _, scope := range r.NotIn {
+			denied = append(denied, scopes[scope]...)
+		}
+		segments = subtractSpans(segments, mergeSpans(denied))
+	}
+	return segments
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReplacer_ReplaceString_WithScopes(t *testing.T) {
+	input := "# cookie の話\n\n" +
+		"ハードウエアーと cookie と [cookie](https://example.com) と ![cookie](cookie.png)\n\n" +
+		"| cookie |\n|---|\n| cookie |\n\n" +
+		"- cookie\n\n" +
+		"> cookie\n"
+
+	tests := []struct {
+		name     string
+		markdown string
+		rules    []Rule
+		expected string
+	}{
+		{
+			name:  "in heading and table cell",
+			rules: []Rule{{Expected: "クッキー", Pattern: "[Cc]ookie", In: []string{ScopeHeading, ScopeTableCell}}},
+			expected: "# クッキー の話\n\n" +
+				"ハードウエアーと cookie と [cookie](https://example.com) と ![cookie](cookie.png)\n\n" +
+				"| クッキー |\n|---|\n| クッキー |\n\n" +
+				"- cookie\n\n" +
+				"> cookie\n",
+		},
+		{
+			name:     "not in link text and image alt",
+			markdown: MarkdownGoldmark,
+			rules:    []Rule{{Expected: "Cookie", Pattern: "cookie", NotIn: []string{ScopeLinkText, ScopeImageAlt}}},
+			expected: "# Cookie の話\n\n" +
+				"ハードウエアーと Cookie と [cookie](https://example.com) と ![cookie](cookie.png)\n\n" +
+				"| Cookie |\n|---|\n| Cookie |\n\n" +
+				"- Cookie\n\n" +
+				"> Cookie\n",
+		},
+		{
+			name:     "in list item and blockquote",
+			markdown: MarkdownGoldmark,
+			rules:    []Rule{{Expected: "Cookie", Pattern: "cookie", In: []string{ScopeListItem, ScopeBlockquote}}},
+			expected: "# cookie の話\n\n" +
+				"ハードウエアーと cookie と [cookie](https://example.com) と ![cookie](cookie.png)\n\n" +
+				"| cookie |\n|---|\n| cookie |\n\n" +
+				"- Cookie\n\n" +
+				"> Cookie\n",
+		},
+		{
+			// 前のルールで文書の長さが変わっても、要素の範囲は正しく追従する
+			name:     "scopes follow earlier replacements",
+			markdown: MarkdownGoldmark,
+			rules: []Rule{
+				{Expected: "ハードウェア", Pattern: "ハードウエアー"},
+				{Expected: "Cookie", Pattern: "cookie", In: []string{ScopeParagraph}, NotIn: []string{ScopeLinkText, ScopeImageAlt, ScopeListItem, ScopeBlockquote}},
+			},
+			expected: "# cookie の話\n\n" +
+				"ハードウェアと Cookie と [cookie](https://example.com) と ![cookie](cookie.png)\n\n" +
+				"| cookie |\n|---|\n| cookie |\n\n" +
+				"- cookie\n\n" +
+				"> cookie\n",
+		},
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Markdown: tt.markdown, Rules: tt.rules}
+			for i := range config.Rules {
+				if err := config.Rules[i].CompilePattern(); err != nil {
+					t.Fatalf("Failed to compile rule %d: %v", i, err)
+				}
+			}
+
+			result := NewReplacerWithLogger(config, logger).ReplaceString(input)
+			if result.Result != tt.expected {
+				t.Errorf("ReplaceString() = %q, want %q", result.Result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRule_CompilePattern_Scopes(t *testing.T) {
+	rule := Rule{Expected: "Cookie", In: []string{"heading", "footnote"}}
+	err := rule.CompilePattern()
+	if err == nil || !strings.Contains(err.Error(), `unknown scope "footnote"`) {
+		t.Errorf("CompilePattern() error = %v, want unknown scope error", err)
+	}
+
+	// 範囲を指定していないルールのIDは変わらず、範囲を指定したルールは別のIDになる
+	plain := Rule{Expected: "Cookie"}
+	scoped := Rule{Expected: "Cookie", In: []string{ScopeHeading}}
+	if plain.DerivedID() == scoped.DerivedID() {
+		t.Errorf("DerivedID() should differ for scoped rules: %s", plain.DerivedID())
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import "sort"
+
+// TextSpan はテキスト内の範囲を表す構造体（Start から End の手前まで、バイト単位）
+type TextSpan struct {
+	Start int
+	End   int
+}
+
+// union は s と other の両方を含む最小の範囲を返す
+func (s TextSpan) union(other TextSpan) TextSpan {
+	return TextSpan{Start: min(s.Start, other.Start), End: max(s.End, other.End)}
+}
+
+// spanMaskByte は保護された範囲を検出用のテキスト上で塗りつぶす文字
+// 改行や空白、Markdownの記号とみなされないよう NUL を使う
+const spanMaskByte = '\x00'
+
+// mergeSpans は範囲を開始位置の順に並べ、重なる範囲や隣接する範囲をまとめる
+func mergeSpans(spans []TextSpan) []TextSpan {
+	sorted := make([]TextSpan, 0, len(spans))
+	for _, s := range spans {
+		if s.End > s.Start {
+			sorted = append(sorted, s)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	var merged []TextSpan
+	for _, s := range sorted {
+		if n := len(merged); n > 0 && s.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, s.End)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// complementSpans は長さ length のテキストのうち、spans（mergeSpans 済み）に含まれない範囲を返す
+func complementSpans(spans []TextSpan, length int) []TextSpan {
+	var segments []TextSpan
+	start := 0
+	for _, s := range spans {
+		if s.Start > start {
+			segments = append(segments, TextSpan{Start: start, End: s.Start})
+		}
+		start = s.End
+	}
+	if start < length {
+		segments = append(segments, TextSpan{Start: start, End: length})
+	}
+	return segments
+}
+
+// maskSpans はテキストの spans の範囲を同じバイト数の spanMaskByte で塗りつぶす
+// 位置を変えずに、保護された範囲の中身を後続の検出から隠すために使う
+func maskSpans(text string, spans []TextSpan) string {
+	if len(spans) == 0 {
+		return text
+	}
+	b := []byte(text)
+	for _, s := range spans {
+		for i := s.Start; i < s.End; i++ {
+			b[i] = spanMaskByte
+		}
+	}
+	return string(b)
+}
+
+// maskMatches は find が返した範囲（FindAllStringIndex の形式）を spans に加え、それを塗りつぶしたテキストを返す
+func maskMatches(text string, spans []TextSpan, find func(string) [][]int) (string, []TextSpan) {
+	var found []TextSpan
+	for _, m := range find(text) {
+		found = append(found, TextSpan{Start: m[0], End: m[1]})
+	}
+	return maskSpans(text, found), append(spans, found...)
+}
+
+// intersectSpans は a と b（いずれも mergeSpans 済み）の両方に含まれる範囲を返す
+func intersectSpans(a, b []TextSpan) []TextSpan {
+	var result []TextSpan
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		start, end := max(a[i].Start, b[j].Start), min(a[i].End, b[j].End)
+		if start < end {
+			result = append(result, TextSpan{Start: start, End: end})
+		}
+		if a[i].End < b[j].End {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+// subtractSpans は a（mergeSpans 済み）から b（mergeSpans 済み）に含まれる範囲を除いた範囲を返す
+func subtractSpans(a, b []TextSpan) []TextSpan {
+	if len(b) == 0 {
+		return a
+	}
+	var result []TextSpan
+	for _, s := range a {
+		result = append(result, intersectSpans([]TextSpan{s}, complementSpans(b, s.End))...)
+	}
+	return result
+}
+
+// shiftSpans は置換箇所の一覧（置換前のテキスト上の位置）に合わせて、範囲を置換後のテキスト上の範囲に移す
+// 範囲の中にある置換箇所は、置換後のテキストも範囲に含める
+func shiftSpans(spans []TextSpan, matches []ruleMatch) []TextSpan {
+	if len(matches) == 0 {
+		return spans
+	}
+	m := editMapFromMatches(matches)
+	shifted := make([]TextSpan, 0, len(spans))
+	for _, s := range spans {
+		start, end := m.toTarget(s.Start, false), m.toTarget(s.End, true)
+		if start < end {
+			shifted = append(shifted, TextSpan{Start: start, End: end})
+		}
+	}
+	return shifted
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeSpans(t *testing.T) {
+	got := mergeSpans([]TextSpan{{10, 12}, {0, 3}, {2, 5}, {5, 7}, {8, 8}, {11, 15}})
+	want := []TextSpan{{0, 7}, {10, 15}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeSpans() = %v, want %v", got, want)
+	}
+}
+
+func TestComplementSpans(t *testing.T) {
+	tests := []struct {
+		name   string
+		spans  []TextSpan
+		length int
+		want   []TextSpan
+	}{
+		{name: "no spans", spans: nil, length: 5, want: []TextSpan{{0, 5}}},
+		{name: "middle", spans: []TextSpan{{2, 4}}, length: 6, want: []TextSpan{{0, 2}, {4, 6}}},
+		{name: "edges", spans: []TextSpan{{0, 2}, {4, 6}}, length: 6, want: []TextSpan{{2, 4}}},
+		{name: "everything", spans: []TextSpan{{0, 6}}, length: 6, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := complementSpans(tt.spans, tt.length); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("complementSpans() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskSpans(t *testing.T) {
+	got := maskSpans("ab`cd`ef", []TextSpan{{2, 6}})
+	if want := "ab\x00\x00\x00\x00ef"; got != want {
+		t.Errorf("maskSpans() = %q, want %q", got, want)
+	}
+}
+
+func TestShiftSpans(t *testing.T) {
+	// "aa `x` bbbb `y`" で "aa" を "A"、"bbbb" を "BBBBBB" に置換した場合
+	spans := []TextSpan{{3, 6}, {12, 15}}
+	matches := []ruleMatch{
+		{Start: 0, End: 2, From: "aa", To: "A"},
+		{Start: 7, End: 11, From: "bbbb", To: "BBBBBB"},
+	}
+	got := shiftSpans(spans, matches)
+	want := []TextSpan{{2, 5}, {13, 16}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("shiftSpans() = %v, want %v", got, want)
+	}
+}
+
+func TestIntersectSpans(t *testing.T) {
+	got := intersectSpans([]TextSpan{{0, 5}, {8, 12}}, []TextSpan{{3, 9}, {11, 20}})
+	want := []TextSpan{{3, 5}, {8, 9}, {11, 12}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("intersectSpans() = %v, want %v", got, want)
+	}
+}
+
+func TestSubtractSpans(t *testing.T) {
+	got := subtractSpans([]TextSpan{{0, 10}, {12, 15}}, []TextSpan{{2, 4}, {9, 13}})
+	want := []TextSpan{{0, 2}, {4, 9}, {13, 15}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("subtractSpans() = %v, want %v", got, want)
+	}
+}
+
+func TestShiftSpans_ContainingMatches(t *testing.T) {
+	// "# cookie の話" の見出しの範囲の中で "cookie" を "クッキー" に置換した場合
+	spans := []TextSpan{{2, 15}, {17, 20}}
+	matches := []ruleMatch{{Start: 2, End: 8, From: "cookie", To: "クッキー"}}
+	got := shiftSpans(spans, matches)
+	want := []TextSpan{{2, 21}, {23, 26}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("shiftSpans() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// suppressionDirectiveRegex は置換を抑止するHTMLコメントのパターン
+//
+//	<!-- grh-disable -->                以降の置換をすべて抑止する
+//	<!-- grh-disable jQuery, Cookie --> 以降の指定したルールの置換を抑止する
+//	<!-- grh-enable -->                 抑止を解除する（ルールを指定した場合はそのルールのみ）
+//	<!-- grh-disable-next-line -->      次の行の置換を抑止する（ルールも指定できる）
+//
+// ルールはIDまたは expected の値で指定し、複数指定する場合はカンマで区切る
+var suppressionDirectiveRegex = regexp.MustCompile(`<!--[ \t]*grh-(disable-next-line|disable|enable)(?:[ \t]+([^\n]*?))?[ \t]*-->`)
+
+// suppression は置換を抑止する範囲を表す
+type suppression struct {
+	start int
+	end   int
+	rules []string // 対象のルール（空の場合はすべてのルール）
+}
+
+// appliesTo は抑止の範囲がルールを対象とするかを判定する
+func (s suppression) appliesTo(rule *Rule) bool {
+	if len(s.rules) == 0 {
+		return true
+	}
+	for _, name := range s.rules {
+		if name == rule.id() || name == rule.Expected {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressionList はテキスト内の置換を抑止する範囲の一覧
+type suppressionList []suppression
+
+// findSuppressions はテキスト内の grh-disable などのコメントから、置換を抑止する範囲を求める
+// grh-disable に対応する grh-enable がない場合は、テキストの末尾までを範囲とする
+func findSuppressions(text string) suppressionList {
+	var list suppressionList
+
+	allStart := -1
+	ruleStarts := make(map[string]int)
+	closeRule := func(name string, end int) {
+		list = append(list, suppression{start: ruleStarts[name], end: end, rules: []string{name}})
+		delete(ruleStarts, name)
+	}
+
+	for _, m := range suppressionDirectiveRegex.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[0], m[1]
+		var rules []string
+		if m[4] >= 0 {
+			for _, name := range strings.Split(text[m[4]:m[5]], ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					rules = append(rules, name)
+				}
+			}
+		}
+
+		switch text[m[2]:m[3]] {
+		case "disable-next-line":
+			// コメントのある行の次の行全体を範囲とする
+			lineEnd := strings.IndexByte(text[end:], '\n')
+			if lineEnd < 0 {
+				continue
+			}
+			nextStart := end + lineEnd + 1
+			nextEnd := len(text)
+			if i := strings.IndexByte(text[nextStart:], '\n'); i >= 0 {
+				nextEnd = nextStart + i
+			}
+			list = append(list, suppression{start: start, end: nextEnd, rules: rules})
+		case "disable":
+			if len(rules) == 0 {
+				if allStart < 0 {
+					allStart = start
+				}
+				continue
+			}
+			for _, name := range rules {
+				if _, ok := ruleStarts[name]; !ok {
+					ruleStarts[name] = start
+				}
+			}
+		case "enable":
+			if len(rules) == 0 {
+				if allStart >= 0 {
+					list = append(list, suppression{start: allStart, end: end})
+					allStart = -1
+				}
+				for name := range ruleStarts {
+					closeRule(name, end)
+				}
+				continue
+			}
+			for _, name := range rules {
+				if _, ok := ruleStarts[name]; ok {
+					closeRule(name, end)
+				}
+			}
+		}
+	}
+
+	if allStart >= 0 {
+		list = append(list, suppression{start: allStart, end: len(text)})
+	}
+	for name := range ruleStarts {
+		closeRule(name, len(text))
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].start < list[j].start
+	})
+	return list
+}
+
+// suppressed は start から end までの範囲がルールの置換を抑止する範囲にかかるかを判定する
+func (list suppressionList) suppressed(rule *Rule, start, end int) bool {
+	for _, s := range list {
+		overlaps := start < s.end && (s.start < end || s.start == start)
+		if overlaps && s.appliesTo(rule) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestReplacer_ReplaceString_WithSuppressions(t *testing.T) {
+	config := &Config{
+		Rules: []Rule{
+			{Expected: "Cookie", Pattern: "[Cc]ookie"},
+			{Expected: "jQuery", Pattern: "[jJ][qQ][uU][eE][rR][yY]"},
+		},
+	}
+
+	for i := range config.Rules {
+		if err := config.Rules[i].CompilePattern(); err != nil {
+			t.Fatalf("Failed to compile rule %d: %v", i, err)
+		}
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	replacer := NewReplacerWithLogger(config, logger)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "disable and enable",
+			input:    "cookie\n<!-- grh-disable -->\ncookie jquery\n<!-- grh-enable -->\ncookie jquery",
+			expected: "Cookie\n<!-- grh-disable -->\ncookie jquery\n<!-- grh-enable -->\nCookie jQuery",
+		},
+		{
+			name:     "disable without enable lasts until the end",
+			input:    "cookie\n<!-- grh-disable -->\ncookie",
+			expected: "Cookie\n<!-- grh-disable -->\ncookie",
+		},
+		{
+			name:     "disable next line",
+			input:    "<!-- grh-disable-next-line -->\ncookie jquery\ncookie jquery",
+			expected: "<!-- grh-disable-next-line -->\ncookie jquery\nCookie jQuery",
+		},
+		{
+			name:     "rule scoped disable",
+			input:    "<!-- grh-disable jQuery -->\ncookie jquery\n<!-- grh-enable jQuery -->\njquery",
+			expected: "<!-- grh-disable jQuery -->\nCookie jquery\n<!-- grh-enable jQuery -->\njQuery",
+		},
+		{
+			name:     "rule scoped disable next line with multiple rules",
+			input:    "<!-- grh-disable-next-line Cookie, jQuery -->\ncookie jquery",
+			expected: "<!-- grh-disable-next-line Cookie, jQuery -->\ncookie jquery",
+		},
+		{
+			name:     "enable without rules ends rule scoped disable",
+			input:    "<!-- grh-disable Cookie -->\ncookie\n<!-- grh-enable -->\ncookie",
+			expected: "<!-- grh-disable Cookie -->\ncookie\n<!-- grh-enable -->\nCookie",
+		},
+		{
+			name:     "directive in code span is ignored",
+			input:    "`<!-- grh-disable -->` cookie",
+			expected: "`<!-- grh-disable -->` Cookie",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := replacer.ReplaceString(tt.input)
+			if result.Result != tt.expected {
+				t.Errorf("ReplaceString() = %q, want %q", result.Result, tt.expected)
+			}
+			// 抑止した箇所は置換箇所として記録しない
+			for _, change := range result.Changes {
+				if got := tt.input[change.Position : change.Position+change.Length]; got != change.From {
+					t.Errorf("Change %+v points to %q in the original", change, got)
+				}
+			}
+		})
+	}
+}
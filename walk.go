@@ -0,0 +1,359 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CollectOptions は処理対象のファイルを集める際の条件を表す構造体
+type CollectOptions struct {
+	Extensions []string // ディレクトリを辿って見つけたファイルのうち対象とする拡張子（空の場合はすべて）
+	Include    []string // 対象とするファイルのglobパターン（空の場合はすべて）
+	Exclude    []string // 対象から外すファイルやディレクトリのglobパターン
+	GitIgnore  bool     // リポジトリのルートから辿ったディレクトリまでにある .gitignore の指定に従う
+}
+
+// CollectFiles は指定されたパスから処理対象のファイルの一覧を作る
+// ディレクトリは再帰的に辿り、ファイルは辞書順に並べる。重複したファイルは1度だけ含める
+// 直接指定されたファイルには拡張子の条件は適用しないが、include/excludeの条件は適用する
+//
+// globパターンはパス区切りに "/" を使い、"*"、"?"、"[...]"、"**" が使える。
+// "/" を含まないパターンはファイル名（ディレクトリ名）と、含むパターンはパス全体と比較する。
+// ディレクトリを辿って見つけたファイルは、辿り始めたディレクトリからの相対パスと比較する。
+func CollectFiles(paths []string, opts CollectOptions) ([]string, error) {
+	include, err := compileGlobs(opts.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compileGlobs(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		files = append(files, p)
+	}
+
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %q: %w", p, err)
+		}
+
+		if !info.IsDir() {
+			if selected(p, include, exclude) {
+				add(p)
+			}
+			continue
+		}
+
+		var ignores []gitIgnore
+		if opts.GitIgnore {
+			ignores, err = parentGitIgnores(p)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = filepath.WalkDir(p, func(current string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(p, current)
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				if current != p {
+					if exclude.matchAny(rel) || ignored(ignores, current, true) {
+						return filepath.SkipDir
+					}
+				}
+				if opts.GitIgnore {
+					ignore, err := loadGitIgnore(current)
+					if err != nil {
+						return err
+					}
+					if ignore != nil {
+						ignores = append(ignores, *ignore)
+					}
+				}
+				return nil
+			}
+
+			if !d.Type().IsRegular() || !hasExtension(current, opts.Extensions) {
+				return nil
+			}
+			if ignored(ignores, current, false) || !selected(rel, include, exclude) {
+				return nil
+			}
+			add(current)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk directory %q: %w", p, err)
+		}
+	}
+
+	return files, nil
+}
+
+// hasExtension はファイルが指定された拡張子のいずれかを持つかを判定する（大文字小文字は区別しない）
+func hasExtension(p string, extensions []string) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(p))
+	for _, e := range extensions {
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		if ext == strings.ToLower(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// selected はファイルがinclude/excludeの条件を満たすかを判定する
+func selected(p string, include, exclude globList) bool {
+	if len(include) > 0 && !include.matchAny(p) {
+		return false
+	}
+	return !exclude.matchAny(p)
+}
+
+// glob はglobパターンをコンパイルしたもの
+type glob struct {
+	re       *regexp.Regexp
+	baseOnly bool // "/" を含まないパターンはファイル名とだけ比較する
+}
+
+// globList はglobパターンの一覧
+type globList []glob
+
+// compileGlobs はglobパターンの一覧をコンパイルする
+func compileGlobs(patterns []string) (globList, error) {
+	var globs globList
+	for _, pattern := range patterns {
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, glob{re: re, baseOnly: !strings.Contains(pattern, "/")})
+	}
+	return globs, nil
+}
+
+// matchAny はパスがいずれかのパターンに一致するかを判定する
+func (gl globList) matchAny(p string) bool {
+	p = path.Clean(filepath.ToSlash(p))
+	for _, g := range gl {
+		target := p
+		if g.baseOnly {
+			target = path.Base(p)
+		}
+		if g.re.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp はglobパターンを同じ文字列に一致する正規表現に変換する
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	p := strings.TrimPrefix(pattern, "./")
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid glob pattern %q: unclosed '['", pattern)
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// gitIgnore は1つの .gitignore ファイルの内容を表す
+type gitIgnore struct {
+	dir   string // .gitignore のあるディレクトリ（絶対パス）
+	rules []gitIgnoreRule
+}
+
+// gitIgnoreRule は .gitignore の1行分のパターンを表す
+type gitIgnoreRule struct {
+	glob    glob
+	negate  bool // "!" で始まるパターン
+	dirOnly bool // "/" で終わるパターン
+}
+
+// parentGitIgnores は dir を含むリポジトリのルートから dir の親ディレクトリまでにある .gitignore を上から順に読み込む
+// dir がリポジトリ（.git のあるディレクトリ）の中にない場合は何も読み込まない
+func parentGitIgnores(dir string) ([]gitIgnore, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %q: %w", dir, err)
+	}
+
+	var parents []string
+	for current := abs; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return nil, nil
+		}
+		parents = append(parents, parent)
+		current = parent
+	}
+
+	var ignores []gitIgnore
+	for i := len(parents) - 1; i >= 0; i-- {
+		ignore, err := loadGitIgnore(parents[i])
+		if err != nil {
+			return nil, err
+		}
+		if ignore != nil {
+			ignores = append(ignores, *ignore)
+		}
+	}
+	return ignores, nil
+}
+
+// loadGitIgnore はディレクトリにある .gitignore を読み込む（存在しない場合はnilを返す）
+func loadGitIgnore(dir string) (*gitIgnore, error) {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open .gitignore in %q: %w", dir, err)
+	}
+	defer file.Close()
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %q: %w", dir, err)
+	}
+	ignore := &gitIgnore{dir: abs}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule gitIgnoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, "\\")
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		// 先頭や途中に "/" を含むパターンは .gitignore のあるディレクトリからの相対パスと比較する
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		re, err := globToRegexp(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in %s: %w", filepath.Join(dir, ".gitignore"), err)
+		}
+		rule.glob = glob{re: re, baseOnly: !anchored}
+		ignore.rules = append(ignore.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read .gitignore in %q: %w", dir, err)
+	}
+	return ignore, nil
+}
+
+// ignored はパスが .gitignore の指定で除外されるかを判定する
+// 上位のディレクトリの .gitignore から順に評価し、最後に一致したパターンに従う
+func ignored(ignores []gitIgnore, p string, isDir bool) bool {
+	if len(ignores) == 0 {
+		return false
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	result := false
+	for _, ignore := range ignores {
+		rel, err := filepath.Rel(ignore.dir, abs)
+		rel = filepath.ToSlash(rel)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		for _, rule := range ignore.rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if (globList{rule.glob}).matchAny(rel) {
+				result = !rule.negate
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,204 @@
+// Copyright 2025 Yoshi Yamaguchi
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grh
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// createTree はテスト用のディレクトリ構成を作成し、ルートディレクトリを返す
+func createTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	return root
+}
+
+func TestCollectFiles(t *testing.T) {
+	root := createTree(t, map[string]string{
+		"index.md":                   "",
+		"notes.txt":                  "",
+		"posts/a.md":                 "",
+		"posts/b.markdown":           "",
+		"posts/drafts/c.md":          "",
+		"posts/_index.md":            "",
+		"static/d.md":                "",
+		"posts/.gitignore":           "drafts/\n",
+		".gitignore":                 "static/\n*.tmp.md\n!keep.tmp.md\n",
+		"posts/e.tmp.md":             "",
+		"posts/keep.tmp.md":          "",
+		".git/COMMIT_EDITMSG.md":     "",
+		"node_modules/module/foo.md": "",
+	})
+
+	rel := func(paths ...string) []string {
+		var result []string
+		for _, p := range paths {
+			result = append(result, filepath.Join(root, filepath.FromSlash(p)))
+		}
+		return result
+	}
+
+	tests := []struct {
+		name  string
+		paths []string
+		opts  CollectOptions
+		want  []string
+	}{
+		{
+			name:  "walk directory with extension filter",
+			paths: []string{root},
+			opts:  CollectOptions{Extensions: []string{".md", ".markdown"}},
+			want: rel("index.md", "node_modules/module/foo.md", "posts/_index.md", "posts/a.md", "posts/b.markdown",
+				"posts/drafts/c.md", "posts/e.tmp.md", "posts/keep.tmp.md", "static/d.md"),
+		},
+		{
+			name:  "exclude by file and directory name",
+			paths: []string{root},
+			opts:  CollectOptions{Extensions: []string{"md"}, Exclude: []string{"node_modules", "_*.md", "**/drafts/**"}},
+			want:  rel("index.md", "posts/a.md", "posts/e.tmp.md", "posts/keep.tmp.md", "static/d.md"),
+		},
+		{
+			name:  "include by path pattern",
+			paths: []string{root},
+			opts:  CollectOptions{Include: []string{"**/posts/*.md"}},
+			want:  rel("posts/_index.md", "posts/a.md", "posts/e.tmp.md", "posts/keep.tmp.md"),
+		},
+		{
+			name:  "honor gitignore",
+			paths: []string{root},
+			opts:  CollectOptions{Extensions: []string{".md"}, Exclude: []string{"node_modules"}, GitIgnore: true},
+			want:  rel("index.md", "posts/_index.md", "posts/a.md", "posts/keep.tmp.md"),
+		},
+		{
+			name:  "explicit file ignores extension filter and duplicates are removed",
+			paths: append(rel("notes.txt", "posts/a.md"), filepath.Join(root, "posts")),
+			opts:  CollectOptions{Extensions: []string{".md"}, Exclude: []string{"drafts"}},
+			want:  rel("notes.txt", "posts/a.md", "posts/_index.md", "posts/e.tmp.md", "posts/keep.tmp.md"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CollectFiles(tt.paths, tt.opts)
+			if err != nil {
+				t.Fatalf("CollectFiles() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CollectFiles() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectFiles_Subdirectory(t *testing.T) {
+	root := createTree(t, map[string]string{
+		".git/HEAD":               "",
+		".gitignore":              "content/drafts/\n",
+		"content/a.md":            "",
+		"content/drafts/b.md":     "",
+		"content/posts/drafts.md": "",
+		"content/posts/old/c.md":  "",
+	})
+	content := filepath.Join(root, "content")
+
+	tests := []struct {
+		name string
+		opts CollectOptions
+		want []string
+	}{
+		{
+			// リポジトリのルートにある .gitignore も適用する
+			name: "gitignore above the walked directory",
+			opts: CollectOptions{Extensions: []string{".md"}, GitIgnore: true},
+			want: []string{"a.md", "posts/drafts.md", "posts/old/c.md"},
+		},
+		{
+			// "/" を含むパターンは辿り始めたディレクトリからの相対パスと比較する
+			name: "exclude relative to the walked directory",
+			opts: CollectOptions{Extensions: []string{".md"}, Exclude: []string{"drafts/**", "posts/old"}},
+			want: []string{"a.md", "posts/drafts.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CollectFiles([]string{content}, tt.opts)
+			if err != nil {
+				t.Fatalf("CollectFiles() error = %v", err)
+			}
+			var want []string
+			for _, p := range tt.want {
+				want = append(want, filepath.Join(content, filepath.FromSlash(p)))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("CollectFiles() =\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestCollectFiles_NotFound(t *testing.T) {
+	if _, err := CollectFiles([]string{"testdata/not-found"}, CollectOptions{}); err == nil {
+		t.Error("Expected error for nonexistent path")
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*.md", path: "a.md", want: true},
+		{pattern: "*.md", path: "dir/a.md", want: false},
+		{pattern: "**/*.md", path: "a.md", want: true},
+		{pattern: "**/*.md", path: "dir/sub/a.md", want: true},
+		{pattern: "content/**", path: "content/a/b.md", want: true},
+		{pattern: "content/**", path: "other/a.md", want: false},
+		{pattern: "a?c.md", path: "abc.md", want: true},
+		{pattern: "[!a]bc.md", path: "abc.md", want: false},
+		{pattern: "[!a]bc.md", path: "xbc.md", want: true},
+		{pattern: "./docs/*.md", path: "docs/a.md", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			re, err := globToRegexp(tt.pattern)
+			if err != nil {
+				t.Fatalf("globToRegexp(%q) error = %v", tt.pattern, err)
+			}
+			if got := re.MatchString(tt.path); got != tt.want {
+				t.Errorf("globToRegexp(%q).MatchString(%q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := globToRegexp("[abc"); err == nil {
+		t.Error("Expected error for unclosed '['")
+	}
+}